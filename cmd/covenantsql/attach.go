@@ -0,0 +1,70 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gitlab.com/thunderdb/ThunderDB/sqlchain/rpc"
+)
+
+// runAttach implements the `covenantsql attach` CLI mode: it connects to a running node's
+// sqlchain/rpc endpoint and evaluates one `method arg...` call per line of input, printing the
+// decoded result in Go's %+v form, much like geth's `attach` console but without a JS runtime.
+func runAttach(args []string) error {
+	fs := flag.NewFlagSet("attach", flag.ExitOnError)
+	addr := fs.String("addr", "http://127.0.0.1:8546", "sqlchain RPC endpoint to attach to")
+	fs.Parse(args)
+
+	client := rpc.NewClient(*addr)
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Fprintf(os.Stderr, "attached to %s\n", *addr)
+
+	for {
+		fmt.Fprint(os.Stderr, "> ")
+
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		params := make([]interface{}, len(fields)-1)
+
+		for i, f := range fields[1:] {
+			params[i] = f
+		}
+
+		var result interface{}
+
+		if err := client.Call(&result, fields[0], params...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+
+		fmt.Printf("%+v\n", result)
+	}
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"flag"
+	"time"
+
+	"gitlab.com/thunderdb/ThunderDB/sqlchain"
+)
+
+// devFlags holds the --dev/--dev.period flags shared by the subcommands that boot a Chain.
+type devFlags struct {
+	enabled bool
+	period  time.Duration
+}
+
+// registerDevFlags adds the dev-mode flags to fs.
+func registerDevFlags(fs *flag.FlagSet) *devFlags {
+	df := &devFlags{}
+	fs.BoolVar(&df.enabled, "dev", false,
+		"run a single-node chain with no real producer turn schedule, for local development")
+	fs.DurationVar(&df.period, "dev.period", 0,
+		"block period in dev mode; 0 produces a block whenever a tx is pending (Commit still forces one)")
+	return df
+}
+
+// apply overlays the dev-mode flags onto cfg.
+func (df *devFlags) apply(cfg *sqlchain.Config) {
+	cfg.DevMode = df.enabled
+	cfg.DevPeriod = df.period
+}
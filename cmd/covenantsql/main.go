@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command covenantsql runs a sqlchain node, or attaches to one that's already running.
+//
+//	covenantsql run [--dev] [--dev.period d] [--datadir dir] [--genesis file] [--rpc.addr addr]
+//	covenantsql attach [--addr addr]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"gitlab.com/thunderdb/ThunderDB/sqlchain"
+	"gitlab.com/thunderdb/ThunderDB/sqlchain/rpc"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: covenantsql <run|attach> [flags]")
+		os.Exit(1)
+	}
+
+	var err error
+
+	switch os.Args[1] {
+	case "attach":
+		err = runAttach(os.Args[2:])
+	case "run":
+		err = runChain(os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown subcommand %q", os.Args[1])
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// runChain implements the `covenantsql run` CLI mode: it loads or creates a chain, serves it over
+// sqlchain/rpc (the JSON-RPC Handler at "/" and the chain_subscribe streaming Handler at
+// "/subscribe"), and runs the block producing cycle until the process is killed or admin_stop
+// closes it down.
+func runChain(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	dataDir := fs.String("datadir", "./covenantsql-data", "path of the chain's bbolt database file")
+	genesisFile := fs.String("genesis", "", "path to a JSON-encoded genesis block; required unless --datadir already holds a chain")
+	rpcAddr := fs.String("rpc.addr", "127.0.0.1:8546", "address to serve sqlchain/rpc on")
+	df := registerDevFlags(fs)
+	fs.Parse(args)
+
+	cfg := &sqlchain.Config{DataDir: *dataDir}
+	df.apply(cfg)
+
+	chain, err := loadOrCreateChain(cfg, *genesisFile)
+
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", rpc.NewHandler(rpc.NewService(chain)))
+	mux.Handle("/subscribe", rpc.NewSubscriptionHandler(chain))
+
+	go chain.BlockProducingCycle()
+
+	fmt.Fprintf(os.Stderr, "serving sqlchain/rpc on %s\n", *rpcAddr)
+	return http.ListenAndServe(*rpcAddr, mux)
+}
+
+// loadOrCreateChain opens the chain already stored at cfg.DataDir, or bootstraps a fresh one from
+// genesisFile if nothing is there yet.
+func loadOrCreateChain(cfg *sqlchain.Config, genesisFile string) (*sqlchain.Chain, error) {
+	if _, err := os.Stat(cfg.DataDir); err == nil {
+		return sqlchain.LoadChain(cfg)
+	}
+
+	if genesisFile == "" {
+		return nil, fmt.Errorf("covenantsql: --genesis is required to bootstrap a new chain at %s", cfg.DataDir)
+	}
+
+	f, err := os.Open(genesisFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	genesis := &sqlchain.Block{}
+
+	if err := json.NewDecoder(f).Decode(genesis); err != nil {
+		return nil, fmt.Errorf("covenantsql: decoding genesis block: %w", err)
+	}
+
+	cfg.Genesis = genesis
+
+	if _, err := os.Stat(cfg.DataDir); err == nil {
+		return sqlchain.LoadChain(cfg)
+	}
+
+	return sqlchain.NewChain(cfg)
+}
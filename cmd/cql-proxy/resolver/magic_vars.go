@@ -16,59 +16,338 @@
 
 package resolver
 
-import "strings"
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
 
+// ErrMissingVar is returned by Template.Apply when a "${name!}" placeholder has no matching
+// entry in the vars map passed to Apply.
+type ErrMissingVar struct {
+	Name string
+}
+
+func (e *ErrMissingVar) Error() string {
+	return fmt.Sprintf("resolver: missing required var %q", e.Name)
+}
+
+// Template is a query pre-compiled by Compile, ready to be applied against any number of var
+// sets without re-parsing its strings each time.
+type Template struct {
+	root node
+}
+
+// Compile parses q once, so that Apply can be called cheaply for every new set of vars.
+func Compile(q map[string]interface{}) *Template {
+	if q == nil {
+		return &Template{}
+	}
+
+	return &Template{root: compileValue(q)}
+}
+
+// Apply resolves every "$name" / "${...}" placeholder in the compiled query against vars and
+// returns the resulting query.
+func (t *Template) Apply(vars map[string]interface{}) (map[string]interface{}, error) {
+	if t.root == nil {
+		return nil, nil
+	}
+
+	result, err := t.root.apply(vars)
+
+	if err != nil {
+		return nil, err
+	}
+
+	injectedQuery, _ := result.(map[string]interface{})
+	return injectedQuery, nil
+}
+
+// InjectMagicVars substitutes every "$name" / "${...}" placeholder found in q against vars. It
+// is equivalent to Compile(q).Apply(vars), kept for callers that only ever apply a query once.
 func InjectMagicVars(q map[string]interface{}, vars map[string]interface{}) (
 	injectedQuery map[string]interface{}, err error) {
-	if q == nil {
-		return
+	return Compile(q).Apply(vars)
+}
+
+// node is a compiled query fragment: a map, a slice, a string with placeholders, or anything
+// else passed through unchanged.
+type node interface {
+	apply(vars map[string]interface{}) (interface{}, error)
+}
+
+type mapNode map[string]node
+
+func (m mapNode) apply(vars map[string]interface{}) (interface{}, error) {
+	out := make(map[string]interface{}, len(m))
+
+	for k, n := range m {
+		v, err := n.apply(vars)
+
+		if err != nil {
+			return nil, err
+		}
+
+		out[k] = v
 	}
 
-	injectedQuery = make(map[string]interface{}, len(q))
+	return out, nil
+}
+
+type sliceNode []node
+
+func (s sliceNode) apply(vars map[string]interface{}) (interface{}, error) {
+	out := make([]interface{}, len(s))
+
+	for i, n := range s {
+		v, err := n.apply(vars)
 
-	for k, v := range q {
-		var r interface{}
-		r, err = processInject(v, vars)
 		if err != nil {
-			return
+			return nil, err
 		}
 
-		injectedQuery[k] = r
+		out[i] = v
 	}
 
-	return
+	return out, nil
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (l literalNode) apply(map[string]interface{}) (interface{}, error) {
+	return l.value, nil
 }
 
-func processInject(v interface{}, vars map[string]interface{}) (r interface{}, err error) {
+// compileValue compiles a single JSON value of any shape into a node.
+func compileValue(v interface{}) node {
 	switch rv := v.(type) {
+	case map[string]interface{}:
+		m := make(mapNode, len(rv))
+
+		for k, vv := range rv {
+			m[k] = compileValue(vv)
+		}
+
+		return m
 	case []interface{}:
-		var subQueryList []interface{}
+		s := make(sliceNode, len(rv))
 
-		for _, ov := range rv {
-			var subQuery interface{}
-			subQuery, err = processInject(ov, vars)
-			if err != nil {
-				return
-			}
+		for i, vv := range rv {
+			s[i] = compileValue(vv)
+		}
+
+		return s
+	case string:
+		return compileString(rv)
+	default:
+		return literalNode{value: v}
+	}
+}
+
+// varRef is a single "$name" / "${...}" placeholder.
+type varRef struct {
+	path       []string
+	required   bool
+	hasDefault bool
+	defaultVal string
+	raw        string // the original "$name" / "${...}" text, echoed back when unresolved
+}
+
+// resolve looks ref up against vars, applying its default/required modifier if it is missing.
+func (ref *varRef) resolve(vars map[string]interface{}) (interface{}, error) {
+	if v, ok := lookupPath(vars, ref.path); ok {
+		return v, nil
+	}
+
+	if ref.required {
+		return nil, &ErrMissingVar{Name: strings.Join(ref.path, ".")}
+	}
+
+	if ref.hasDefault {
+		return ref.defaultVal, nil
+	}
+
+	// No var, no default: leave the placeholder untouched, same as the var simply not existing.
+	return ref.raw, nil
+}
+
+// lookupPath walks vars along path, descending into nested map[string]interface{} values for
+// each dotted segment.
+func lookupPath(vars map[string]interface{}, path []string) (interface{}, bool) {
+	var cur interface{} = vars
+
+	for _, p := range path {
+		m, ok := cur.(map[string]interface{})
 
-			subQueryList = append(subQueryList, subQuery)
+		if !ok {
+			return nil, false
 		}
 
-		r = subQueryList
-	case map[string]interface{}:
-		return InjectMagicVars(rv, vars)
+		cur, ok = m[p]
+
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return cur, true
+}
+
+// parseRef parses the inside of a "${...}" placeholder: a dotted path, optionally followed by
+// "!" (required) or ":-fallback" (default).
+func parseRef(expr string) *varRef {
+	ref := &varRef{raw: "${" + expr + "}"}
+
+	if strings.HasSuffix(expr, "!") {
+		ref.required = true
+		expr = strings.TrimSuffix(expr, "!")
+	} else if idx := strings.Index(expr, ":-"); idx >= 0 {
+		ref.hasDefault = true
+		ref.defaultVal = expr[idx+2:]
+		expr = expr[:idx]
+	}
+
+	ref.path = strings.Split(expr, ".")
+	return ref
+}
+
+// segment is one piece of a compiled string: either a literal run of text or a placeholder.
+type segment struct {
+	literal string
+	ref     *varRef
+}
+
+// compiledString is a string value with its "$name" / "${...}" placeholders pre-parsed.
+type compiledString struct {
+	segments []segment
+	// wholeRef is set when the entire original string was exactly one placeholder, so Apply can
+	// return the variable's native JSON type instead of stringifying it.
+	wholeRef *varRef
+}
+
+func (cs *compiledString) apply(vars map[string]interface{}) (interface{}, error) {
+	if cs.wholeRef != nil {
+		return cs.wholeRef.resolve(vars)
+	}
+
+	var b strings.Builder
+
+	for _, seg := range cs.segments {
+		if seg.ref == nil {
+			b.WriteString(seg.literal)
+			continue
+		}
+
+		v, err := seg.ref.resolve(vars)
+
+		if err != nil {
+			return nil, err
+		}
+
+		b.WriteString(coerceToString(v))
+	}
+
+	return b.String(), nil
+}
+
+// coerceToString renders a resolved value for mid-string interpolation.
+func coerceToString(v interface{}) string {
+	switch rv := v.(type) {
+	case nil:
+		return ""
 	case string:
-		if !strings.HasPrefix("$", rv) {
-			r = v
-		} else if injectedVar, ok := vars[rv[1:]]; !ok {
-			r = v
-		} else {
-			r = injectedVar
+		return rv
+	case map[string]interface{}, []interface{}:
+		if buf, err := json.Marshal(rv); err == nil {
+			return string(buf)
 		}
+		return fmt.Sprintf("%v", rv)
 	default:
-		// let it be
-		r = v
+		return fmt.Sprintf("%v", rv)
+	}
+}
+
+// isIdentByte reports whether b can be part of a single path segment of a bare "$name"
+// reference. "." is deliberately excluded: it separates path segments rather than belonging to
+// one, so it is handled on its own in compileString's scan instead.
+func isIdentByte(b byte) bool {
+	return b == '_' ||
+		(b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+// compileString parses s into a compiledString, recognising both braced "${name...}"
+// placeholders and bare "$name" references.
+func compileString(s string) *compiledString {
+	var segs []segment
+	i := 0
+
+	for i < len(s) {
+		j := strings.IndexByte(s[i:], '$')
+
+		if j < 0 {
+			segs = append(segs, segment{literal: s[i:]})
+			break
+		}
+
+		j += i
+
+		if j > i {
+			segs = append(segs, segment{literal: s[i:j]})
+		}
+
+		if j+1 < len(s) && s[j+1] == '{' {
+			end := strings.IndexByte(s[j+2:], '}')
+
+			if end < 0 {
+				segs = append(segs, segment{literal: s[j:]})
+				break
+			}
+
+			end += j + 2
+			segs = append(segs, segment{ref: parseRef(s[j+2 : end])})
+			i = end + 1
+			continue
+		}
+
+		k := j + 1
+
+		for k < len(s) {
+			if isIdentByte(s[k]) {
+				k++
+				continue
+			}
+
+			// A "." only continues the name when it joins two identifier segments (a dotted
+			// path); a trailing "." (end of sentence, end of string, ...) is left as literal
+			// text instead of being swallowed into the var name.
+			if s[k] == '.' && k+1 < len(s) && isIdentByte(s[k+1]) {
+				k++
+				continue
+			}
+
+			break
+		}
+
+		if k == j+1 {
+			// A lone "$" with nothing recognizable after it: keep it as literal text.
+			segs = append(segs, segment{literal: "$"})
+			i = j + 1
+			continue
+		}
+
+		name := s[j+1 : k]
+		segs = append(segs, segment{ref: &varRef{path: strings.Split(name, "."), raw: "$" + name}})
+		i = k
+	}
+
+	cs := &compiledString{segments: segs}
+
+	if len(segs) == 1 && segs[0].ref != nil {
+		cs.wholeRef = segs[0].ref
 	}
 
-	return
+	return cs
 }
@@ -0,0 +1,203 @@
+/*
+ * Copyright 2019 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInjectMagicVars(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   map[string]interface{}
+		vars    map[string]interface{}
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:  "bare var preserves type",
+			query: map[string]interface{}{"limit": "$count"},
+			vars:  map[string]interface{}{"count": 42},
+			want:  map[string]interface{}{"limit": 42},
+		},
+		{
+			name:  "braced var preserves type",
+			query: map[string]interface{}{"limit": "${count}"},
+			vars:  map[string]interface{}{"count": 42},
+			want:  map[string]interface{}{"limit": 42},
+		},
+		{
+			name:  "mid-string interpolation coerces to string",
+			query: map[string]interface{}{"name": "hello ${who}!"},
+			vars:  map[string]interface{}{"who": "world"},
+			want:  map[string]interface{}{"name": "hello world!"},
+		},
+		{
+			name:  "mid-string interpolation of a number",
+			query: map[string]interface{}{"name": "item-${id}"},
+			vars:  map[string]interface{}{"id": 7},
+			want:  map[string]interface{}{"name": "item-7"},
+		},
+		{
+			name:  "dotted path",
+			query: map[string]interface{}{"id": "${user.profile.id}"},
+			vars: map[string]interface{}{
+				"user": map[string]interface{}{
+					"profile": map[string]interface{}{"id": 9},
+				},
+			},
+			want: map[string]interface{}{"id": 9},
+		},
+		{
+			name:  "default used when var is missing",
+			query: map[string]interface{}{"limit": "${count:-10}"},
+			vars:  map[string]interface{}{},
+			want:  map[string]interface{}{"limit": "10"},
+		},
+		{
+			name:  "default not used when var is present",
+			query: map[string]interface{}{"limit": "${count:-10}"},
+			vars:  map[string]interface{}{"count": 5},
+			want:  map[string]interface{}{"limit": 5},
+		},
+		{
+			name:    "required var missing returns ErrMissingVar",
+			query:   map[string]interface{}{"id": "${user.id!}"},
+			vars:    map[string]interface{}{},
+			wantErr: true,
+		},
+		{
+			name:  "required var present resolves",
+			query: map[string]interface{}{"id": "${user.id!}"},
+			vars: map[string]interface{}{
+				"user": map[string]interface{}{"id": 3},
+			},
+			want: map[string]interface{}{"id": 3},
+		},
+		{
+			name:  "missing var with no default is left untouched",
+			query: map[string]interface{}{"id": "$missing"},
+			vars:  map[string]interface{}{},
+			want:  map[string]interface{}{"id": "$missing"},
+		},
+		{
+			name:  "bare var followed by sentence-ending punctuation",
+			query: map[string]interface{}{"msg": "Order is $count."},
+			vars:  map[string]interface{}{"count": 5},
+			want:  map[string]interface{}{"msg": "Order is 5."},
+		},
+		{
+			name:  "bare var with dotted path",
+			query: map[string]interface{}{"id": "$user.profile.id"},
+			vars: map[string]interface{}{
+				"user": map[string]interface{}{
+					"profile": map[string]interface{}{"id": 9},
+				},
+			},
+			want: map[string]interface{}{"id": 9},
+		},
+		{
+			name: "nested objects recurse",
+			query: map[string]interface{}{
+				"filter": map[string]interface{}{"owner": "$user"},
+			},
+			vars: map[string]interface{}{"user": "alice"},
+			want: map[string]interface{}{
+				"filter": map[string]interface{}{"owner": "alice"},
+			},
+		},
+		{
+			name: "array of objects keeps element positions",
+			query: map[string]interface{}{
+				"items": []interface{}{
+					map[string]interface{}{"id": "$a"},
+					map[string]interface{}{"id": "$b"},
+				},
+			},
+			vars: map[string]interface{}{"a": 1, "b": 2},
+			want: map[string]interface{}{
+				"items": []interface{}{
+					map[string]interface{}{"id": 1},
+					map[string]interface{}{"id": 2},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InjectMagicVars(tt.query, tt.vars)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+
+				if _, ok := err.(*ErrMissingVar); !ok {
+					t.Fatalf("expected *ErrMissingVar, got %T", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileAppliesToMultipleVarSets(t *testing.T) {
+	tmpl := Compile(map[string]interface{}{"id": "$id"})
+
+	got1, err := tmpl.Apply(map[string]interface{}{"id": 1})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got1["id"] != 1 {
+		t.Fatalf("expected id 1, got %v", got1["id"])
+	}
+
+	got2, err := tmpl.Apply(map[string]interface{}{"id": 2})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got2["id"] != 2 {
+		t.Fatalf("expected id 2, got %v", got2["id"])
+	}
+}
+
+func TestInjectMagicVarsNilQuery(t *testing.T) {
+	got, err := InjectMagicVars(nil, map[string]interface{}{"a": 1})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != nil {
+		t.Fatalf("expected nil result for nil query, got %#v", got)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlchain
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/thunderdb/ThunderDB/crypto/kms"
+	"gitlab.com/thunderdb/ThunderDB/sqlchain/storage"
+)
+
+// buildBranch signs n blocks extending tip (whose height is tipHeight), the way ProduceBlock
+// would, spacing their timestamps one period apart so each passes AdviseNewBlock's producing-time
+// window check.
+func buildBranch(t *testing.T, genesisTime time.Time, period time.Duration, tip *SignedHeader, tipHeight int32, n int, seed byte) []*Block {
+	t.Helper()
+
+	priv, err := kms.GetLocalPrivateKey()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := make([]*Block, 0, n)
+	parent := tip
+
+	for i := 0; i < n; i++ {
+		height := tipHeight + int32(i)
+		header := &SignedHeader{
+			Header:    Header{ParentHash: parent.BlockHash},
+			Timestamp: genesisTime.Add(time.Duration(height+1)*period + period/2),
+		}
+		header.RootHash[0] = seed
+		header.RootHash[1] = byte(i)
+
+		block := &Block{SignedHeader: header}
+
+		if err := block.SignHeader(priv); err != nil {
+			t.Fatal(err)
+		}
+
+		blocks = append(blocks, block)
+		parent = block.SignedHeader
+	}
+
+	return blocks
+}
+
+// TestAdviseNewBlockPersistsCompetingBranchesAndFlipsHead builds two competing 3-block branches
+// off the same genesis, advises both through the real Chain (backed by storage.OpenMemory, so no
+// filesystem is touched), and checks that both branches land in the index/store and that
+// state.Head ends up on the fork-choice winner: both branches carry equal work, so that is the tip
+// with the lower hash, which flips the head away from branch A when branch B's tip hash is lower.
+func TestAdviseNewBlockPersistsCompetingBranchesAndFlipsHead(t *testing.T) {
+	priv, err := kms.GetLocalPrivateKey()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genesisTime := time.Now()
+	genesisHeader := &SignedHeader{Timestamp: genesisTime}
+	genesisHeader.Header.ParentHash = genesisHeader.RootHash
+	genesis := &Block{SignedHeader: genesisHeader}
+
+	if err := genesis.SignHeader(priv); err != nil {
+		t.Fatal(err)
+	}
+
+	period := time.Hour
+	cfg := &Config{Genesis: genesis, Period: period, Store: storage.OpenMemory()}
+
+	chain, err := NewChain(cfg)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	branchA := buildBranch(t, genesisTime, period, genesis.SignedHeader, 0, 3, 0xaa)
+	branchB := buildBranch(t, genesisTime, period, genesis.SignedHeader, 0, 3, 0xbb)
+
+	for _, b := range branchA {
+		if err := chain.AdviseNewBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, b := range branchB {
+		if err := chain.AdviseNewBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tipA := branchA[len(branchA)-1].SignedHeader.BlockHash
+	tipB := branchB[len(branchB)-1].SignedHeader.BlockHash
+
+	if !chain.index.HasBlock(&tipA) || !chain.index.HasBlock(&tipB) {
+		t.Fatal("expected both competing branches to be present in the index")
+	}
+
+	nodeA := chain.index.LookupNode(&tipA)
+	nodeB := chain.index.LookupNode(&tipB)
+
+	want := tipA
+	if better(nodeB, nodeA) {
+		want = tipB
+	}
+
+	if chain.state.Head != want {
+		t.Fatal("expected state.Head to be the fork-choice winner's tip")
+	}
+
+	loser, winner := branchB, branchA
+	if want == tipB {
+		loser, winner = branchA, branchB
+	}
+
+	for _, blocks := range [][]*Block{loser, winner} {
+		for _, b := range blocks {
+			got, err := chain.BlockByHash(b.SignedHeader.BlockHash)
+
+			if err != nil {
+				t.Fatalf("expected every persisted block to be retrievable, got %v", err)
+			}
+
+			if got.SignedHeader.BlockHash != b.SignedHeader.BlockHash {
+				t.Fatal("retrieved the wrong block")
+			}
+		}
+	}
+}
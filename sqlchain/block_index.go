@@ -0,0 +1,191 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlchain
+
+import (
+	"bytes"
+	"sync"
+
+	"gitlab.com/thunderdb/ThunderDB/crypto/hash"
+)
+
+// blockNode represents a block position in the index, together with everything fork-choice needs
+// to know about it. Unlike Block/SignedHeader, which are throwaway wire objects, a blockNode is
+// kept around for the lifetime of the index so that side branches can be compared and, if they
+// turn out to win, replayed without re-reading every header from disk.
+type blockNode struct {
+	parent  *blockNode
+	hash    hash.Hash
+	height  int32
+	workSum uint64
+	header  *SignedHeader
+	// block is the full block this node was created from, when available. It is only ever set
+	// for blocks advised through AdviseNewBlock in the current process lifetime (notably not for
+	// nodes rebuilt by LoadChain), and is what gets handed to connect/disconnect callbacks.
+	block *Block
+}
+
+// newBlockNode creates a new block node pointing to the given parent.
+func newBlockNode(header *SignedHeader, parent *blockNode) *blockNode {
+	node := &blockNode{}
+	node.initBlockNode(header, parent)
+	return node
+}
+
+// initBlockNode initializes node in place from header and parent, so that callers filling a
+// pre-allocated slice of nodes (see LoadChain) don't have to take the address of a fresh copy.
+func (n *blockNode) initBlockNode(header *SignedHeader, parent *blockNode) {
+	n.header = header
+	n.hash = header.BlockHash
+	n.parent = parent
+	n.height = 0
+	n.workSum = blockWeight(header)
+
+	if parent != nil {
+		n.height = parent.height + 1
+		n.workSum += parent.workSum
+	}
+}
+
+// indexKey returns the bbolt key under which this node's header is stored: big-endian height
+// followed by the block hash, so that a cursor walk yields blocks in height order even across
+// side branches sharing the same height.
+func (n *blockNode) indexKey() (key []byte) {
+	key = make([]byte, 4+hash.HashSize)
+	key[0] = byte(n.height >> 24)
+	key[1] = byte(n.height >> 16)
+	key[2] = byte(n.height >> 8)
+	key[3] = byte(n.height)
+	copy(key[4:], n.hash[:])
+	return
+}
+
+// ancestor returns the ancestor of n at the given height, or nil if height is out of range.
+func (n *blockNode) ancestor(height int32) *blockNode {
+	if height < 0 || height > n.height {
+		return nil
+	}
+
+	node := n
+
+	for node != nil && node.height > height {
+		node = node.parent
+	}
+
+	return node
+}
+
+// blockWeight returns the fork-choice weight contributed by a single block. The chain currently
+// has no notion of difficulty, so every block simply counts for one unit of cumulative work;
+// height and workSum therefore move in lockstep until a real weighting scheme is needed.
+func blockWeight(header *SignedHeader) uint64 {
+	return 1
+}
+
+// better reports whether candidate should replace current as the chain tip: highest cumulative
+// work wins, ties are broken by the lowest block hash so that all nodes converge deterministically
+// without needing to agree on arrival order.
+func better(candidate, current *blockNode) bool {
+	if current == nil {
+		return true
+	}
+
+	if candidate.workSum != current.workSum {
+		return candidate.workSum > current.workSum
+	}
+
+	return bytes.Compare(candidate.hash[:], current.hash[:]) < 0
+}
+
+// findFork walks two branches back to their common ancestor.
+func findFork(a, b *blockNode) *blockNode {
+	for a.height > b.height {
+		a = a.parent
+	}
+
+	for b.height > a.height {
+		b = b.parent
+	}
+
+	for a != b {
+		a = a.parent
+		b = b.parent
+	}
+
+	return a
+}
+
+// blockIndex is an in-memory index of every block known to the chain, including side-branch
+// blocks that never became part of the best chain. It is the thing AdviseNewBlock consults to
+// decide whether an incoming block extends, forks from, or is orphaned against what is known.
+type blockIndex struct {
+	cfg *Config
+
+	mu    sync.RWMutex
+	index map[hash.Hash]*blockNode
+
+	orphanMu sync.Mutex
+	orphans  map[hash.Hash][]*Block // keyed by the orphan's missing parent hash
+}
+
+// newBlockIndex creates a new empty blockIndex.
+func newBlockIndex(cfg *Config) *blockIndex {
+	return &blockIndex{
+		cfg:     cfg,
+		index:   make(map[hash.Hash]*blockNode),
+		orphans: make(map[hash.Hash][]*Block),
+	}
+}
+
+// AddBlock registers node in the index, making it visible to HasBlock/LookupNode regardless of
+// whether it ends up on the best chain.
+func (i *blockIndex) AddBlock(node *blockNode) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.index[node.hash] = node
+}
+
+// HasBlock returns whether the given block hash is already known to the index.
+func (i *blockIndex) HasBlock(h *hash.Hash) bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	_, ok := i.index[*h]
+	return ok
+}
+
+// LookupNode returns the node for the given block hash, or nil if it is unknown.
+func (i *blockIndex) LookupNode(h *hash.Hash) *blockNode {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.index[*h]
+}
+
+// addOrphan stashes block until its parent, identified by parentHash, shows up.
+func (i *blockIndex) addOrphan(parentHash hash.Hash, block *Block) {
+	i.orphanMu.Lock()
+	defer i.orphanMu.Unlock()
+	i.orphans[parentHash] = append(i.orphans[parentHash], block)
+}
+
+// popOrphans removes and returns every orphan that was waiting on parentHash.
+func (i *blockIndex) popOrphans(parentHash hash.Hash) (blocks []*Block) {
+	i.orphanMu.Lock()
+	defer i.orphanMu.Unlock()
+	blocks = i.orphans[parentHash]
+	delete(i.orphans, parentHash)
+	return
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlchain
+
+import (
+	"bytes"
+	"testing"
+
+	"gitlab.com/thunderdb/ThunderDB/crypto/hash"
+)
+
+// chainFrom extends parent with n nodes whose hashes are derived from seed, so that two branches
+// built from different seeds never collide.
+func chainFrom(parent *blockNode, n int, seed byte) (tip *blockNode) {
+	tip = parent
+
+	for i := 0; i < n; i++ {
+		node := &blockNode{parent: tip, height: tip.height + 1, workSum: tip.workSum + 1}
+		node.hash[0] = seed
+		node.hash[hash.HashSize-1] = byte(i)
+		tip = node
+	}
+
+	return
+}
+
+func TestForkChoiceBreaksTieByLowestHash(t *testing.T) {
+	genesis := &blockNode{height: 0, workSum: 1}
+
+	branchA := chainFrom(genesis, 3, 0xaa)
+	branchB := chainFrom(genesis, 3, 0xbb)
+
+	index := newBlockIndex(nil)
+	index.AddBlock(genesis)
+	for n := branchA; n != genesis; n = n.parent {
+		index.AddBlock(n)
+	}
+	for n := branchB; n != genesis; n = n.parent {
+		index.AddBlock(n)
+	}
+
+	if !index.HasBlock(&branchA.hash) || !index.HasBlock(&branchB.hash) {
+		t.Fatal("expected both competing branches to be present in the index")
+	}
+
+	if !better(branchA, branchB) && !better(branchB, branchA) {
+		t.Fatal("expected one equal-length branch to win the tie-break")
+	}
+
+	// With equal work, the branch with the lower tip hash must win.
+	want, other := branchB, branchA
+	if bytes.Compare(branchA.hash[:], branchB.hash[:]) < 0 {
+		want, other = branchA, branchB
+	}
+
+	if !better(want, other) || better(other, want) {
+		t.Fatal("expected the branch with the lower tip hash to win the tie-break")
+	}
+
+	if !better(want, genesis) {
+		t.Fatal("expected the winning branch to beat an empty/lighter tip")
+	}
+
+	if fork := findFork(branchA, branchB); fork != genesis {
+		t.Fatal("expected the common ancestor of both branches to be genesis")
+	}
+}
+
+func TestForkChoicePrefersHeavierBranch(t *testing.T) {
+	genesis := &blockNode{height: 0, workSum: 1}
+
+	short := chainFrom(genesis, 2, 0x01)
+	long := chainFrom(genesis, 3, 0x02)
+
+	if !better(long, short) {
+		t.Fatal("expected the branch with more cumulative work to win regardless of hash")
+	}
+
+	if better(short, long) {
+		t.Fatal("did not expect the lighter branch to win")
+	}
+}
+
+func TestBlockNodeIndexKeyOrdersByHeightThenHash(t *testing.T) {
+	genesis := &blockNode{height: 0, workSum: 1}
+	child := chainFrom(genesis, 1, 0xff)
+
+	if bytes.Compare(genesis.indexKey(), child.indexKey()) >= 0 {
+		t.Fatal("expected a lower block to sort before its child regardless of hash bytes")
+	}
+
+	sibling := chainFrom(genesis, 1, 0x01)
+
+	if bytes.Compare(sibling.indexKey(), child.indexKey()) >= 0 {
+		t.Fatal("expected blocks at the same height to sort by hash")
+	}
+}
+
+func TestBlockIndexOrphanPool(t *testing.T) {
+	index := newBlockIndex(nil)
+
+	var missingParent hash.Hash
+	missingParent[0] = 0x42
+
+	block := &Block{}
+	index.addOrphan(missingParent, block)
+
+	popped := index.popOrphans(missingParent)
+
+	if len(popped) != 1 || popped[0] != block {
+		t.Fatal("expected the orphan to be returned once its parent hash is looked up")
+	}
+
+	if popped = index.popOrphans(missingParent); len(popped) != 0 {
+		t.Fatal("expected orphans to be removed from the pool once popped")
+	}
+}
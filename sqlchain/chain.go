@@ -22,9 +22,10 @@ import (
 	"sync"
 	"time"
 
-	bolt "github.com/coreos/bbolt"
 	"gitlab.com/thunderdb/ThunderDB/crypto/hash"
 	"gitlab.com/thunderdb/ThunderDB/crypto/kms"
+	"gitlab.com/thunderdb/ThunderDB/sqlchain/events"
+	"gitlab.com/thunderdb/ThunderDB/sqlchain/storage"
 	"gitlab.com/thunderdb/ThunderDB/utils"
 )
 
@@ -64,7 +65,8 @@ type Runtime struct {
 	// NextHeight is the height of the next block.
 	NextHeight int32
 
-	stopCh chan struct{}
+	stopCh   chan struct{}
+	stopOnce sync.Once
 }
 
 // UpdateTime updates the current coodinated chain time.
@@ -87,8 +89,13 @@ func (r *Runtime) GotoNextTurn() {
 	r.NextHeight++
 }
 
+// Stop closes stopCh, signalling BlockProducingCycle to return. It is idempotent: admin_stop is
+// reachable over unauthenticated RPC, so a second call (or a concurrent one) must not panic on a
+// close of an already-closed channel.
 func (r *Runtime) Stop() {
-	close(r.stopCh)
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
 }
 
 func (r *Runtime) TillNextWakeUp() time.Duration {
@@ -119,13 +126,40 @@ func (s *State) unmarshal(b []byte) (err error) {
 // Chain represents a sql-chain.
 type Chain struct {
 	cfg          *Config
-	db           *bolt.DB
+	db           storage.KV
 	index        *blockIndex
 	rt           *Runtime
 	pendingBlock *Block
-	state        *State
+
+	// stateMu protects state's fields from the data race between reorganize/PushBlock (the sole
+	// writers, serialized by db.Update) and the RPC handlers' reader.go accessors, which run as
+	// ordinary concurrent goroutines once a chain is served over HTTP.
+	stateMu sync.RWMutex
+	state   *State
+
+	disconnectCBs []DisconnectBlockCallback
+	connectCBs    []ConnectBlockCallback
+
+	// pendingMu protects pendingTxs, the minimal stand-in for the real tx pool: just enough for
+	// hasPendingQueries to observe something real instead of dead code.
+	pendingMu  sync.Mutex
+	pendingTxs []*Tx
+
+	headFeed      events.Feed
+	sideFeed      events.Feed
+	reorgFeed     events.Feed
+	newTxFeed     events.Feed
+	removedTxFeed events.Feed
+
+	broadcast func(*Block) error
 }
 
+// devIdlePoll is how often BlockProducingCycle wakes up to check for a stop signal and a pending
+// tx while in on-demand dev mode (DevMode with DevPeriod == 0), where blocks are produced as
+// pending txs arrive rather than on a fixed period. Commit() can still force a block between
+// polls.
+const devIdlePoll = 50 * time.Millisecond
+
 // NewChain creates a new sql-chain struct.
 func NewChain(cfg *Config) (chain *Chain, err error) {
 	err = cfg.Genesis.VerifyAsGenesis()
@@ -134,15 +168,15 @@ func NewChain(cfg *Config) (chain *Chain, err error) {
 		return
 	}
 
-	// Open DB file
-	db, err := bolt.Open(cfg.DataDir, 0600, nil)
+	// Open the store: either the one the caller supplied, or a bolt database at cfg.DataDir.
+	db, err := cfg.store()
 
 	if err != nil {
 		return
 	}
 
 	// Create buckets for chain meta
-	err = db.Update(func(tx *bolt.Tx) (err error) {
+	err = db.Update(func(tx storage.Tx) (err error) {
 		bucket, err := tx.CreateBucketIfNotExists(metaBucket[:])
 
 		if err != nil {
@@ -164,7 +198,7 @@ func NewChain(cfg *Config) (chain *Chain, err error) {
 		index: newBlockIndex(cfg),
 		rt: &Runtime{
 			Offset:        time.Duration(0),
-			Period:        cfg.Period,
+			Period:        cfg.period(),
 			ChainInitTime: cfg.Genesis.SignedHeader.Timestamp,
 			NextHeight:    1,
 			stopCh:        make(chan struct{}),
@@ -188,8 +222,8 @@ func NewChain(cfg *Config) (chain *Chain, err error) {
 
 // LoadChain loads the chain state from the specified database and rebuilds a memory index.
 func LoadChain(cfg *Config) (chain *Chain, err error) {
-	// Open DB file
-	db, err := bolt.Open(cfg.DataDir, 0600, nil)
+	// Open the store: either the one the caller supplied, or a bolt database at cfg.DataDir.
+	db, err := cfg.store()
 
 	if err != nil {
 		return
@@ -202,7 +236,7 @@ func LoadChain(cfg *Config) (chain *Chain, err error) {
 		index: newBlockIndex(cfg),
 		rt: &Runtime{
 			Offset:        time.Duration(0),
-			Period:        cfg.Period,
+			Period:        cfg.period(),
 			ChainInitTime: cfg.Genesis.SignedHeader.Timestamp,
 			NextHeight:    1,
 			stopCh:        make(chan struct{}),
@@ -211,10 +245,10 @@ func LoadChain(cfg *Config) (chain *Chain, err error) {
 		state:        &State{},
 	}
 
-	err = chain.db.View(func(tx *bolt.Tx) (err error) {
+	err = chain.db.View(func(tx storage.Tx) (err error) {
 		// Read state struct
 		bucket := tx.Bucket(metaBucket[:])
-		err = chain.state.unmarshal(bucket.Get(metaStateKey))
+		err = storage.DefaultCodec.Decode(bucket.Get(metaStateKey), chain.state.unmarshal)
 
 		if err != nil {
 			return err
@@ -236,7 +270,7 @@ func LoadChain(cfg *Config) (chain *Chain, err error) {
 
 		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
 			header := &SignedHeader{}
-			err = header.unmarshal(v)
+			err = storage.DefaultCodec.Decode(v, header.unmarshal)
 
 			if err != nil {
 				return err
@@ -285,16 +319,18 @@ func (c *Chain) PushBlock(block *SignedHeader) (err error) {
 	}
 
 	// Update best state
+	c.stateMu.Lock()
 	c.state.node = newBlockNode(block, c.state.node)
 	c.state.Head = [32]byte(block.BlockHash)
 	c.state.Height++
+	c.stateMu.Unlock()
 
 	// Update index
 	c.index.AddBlock(c.state.node)
 
 	// Write to db
-	return c.db.Update(func(tx *bolt.Tx) (err error) {
-		buffer, err := block.marshal()
+	err = c.db.Update(func(tx storage.Tx) (err error) {
+		buffer, err := storage.DefaultCodec.Encode(block.marshal)
 
 		if err != nil {
 			return err
@@ -307,7 +343,7 @@ func (c *Chain) PushBlock(block *SignedHeader) (err error) {
 			return err
 		}
 
-		buffer, err = c.state.marshal()
+		buffer, err = storage.DefaultCodec.Encode(c.state.marshal)
 
 		if err != nil {
 			return err
@@ -317,22 +353,44 @@ func (c *Chain) PushBlock(block *SignedHeader) (err error) {
 
 		return
 	})
+
+	if err != nil {
+		return err
+	}
+
+	c.headFeed.Send(ChainHeadEvent{Block: c.state.node.asBlock()})
+	return nil
 }
 
 // AdviseNewBlock implements ChainRPCServer.AdviseNewBlock.
+//
+// Unlike PushBlock, which only ever extends the current best chain, AdviseNewBlock accepts any
+// block whose parent is already known to the index, even if that parent is not on the best chain.
+// The block is always indexed and persisted; if its branch turns out to out-weigh the current
+// best chain, AdviseNewBlock reorganizes the chain onto it.
 func (c *Chain) AdviseNewBlock(block *Block) (err error) {
 	// TODO(leventeliu): verify that block.SignedHeader.Producer is the rightful producer of the
 	// block.
 
+	blockHash := block.SignedHeader.BlockHash
+
 	// Check block existence
-	if c.index.HasBlock(&block.SignedHeader.BlockHash) {
+	if c.index.HasBlock(&blockHash) {
 		return ErrBlockExists
 	}
 
-	// Verify block producing time
+	// The parent may be on a side branch: look it up in the full index, not just the best chain.
+	parent := c.index.LookupNode(&block.SignedHeader.Header.ParentHash)
+
+	if parent == nil {
+		c.index.addOrphan(block.SignedHeader.Header.ParentHash, block)
+		return ErrOrphanBlock
+	}
+
+	// Verify block producing time against the branch it extends, not the best chain.
 	start := c.cfg.Genesis.SignedHeader.Timestamp.Add(
-		time.Duration(c.state.Height+1) * c.cfg.Period)
-	end := start.Add(c.cfg.Period)
+		time.Duration(parent.height+1) * c.cfg.period())
+	end := start.Add(c.cfg.period())
 	ptime := block.SignedHeader.Timestamp
 
 	if ptime.Before(start) || (ptime.Equal(end) || ptime.After(end)) {
@@ -344,16 +402,70 @@ func (c *Chain) AdviseNewBlock(block *Block) (err error) {
 		return
 	}
 
-	return c.PushBlock(block.SignedHeader)
+	node := newBlockNode(block.SignedHeader, parent)
+	node.block = block
+	c.index.AddBlock(node)
+
+	if err = c.db.Update(func(tx storage.Tx) (err error) {
+		buffer, err := storage.DefaultCodec.Encode(block.SignedHeader.marshal)
+
+		if err != nil {
+			return err
+		}
+
+		bucket := tx.Bucket(metaBucket[:]).Bucket(metaBlockIndexBucket)
+
+		if err = bucket.Put(node.indexKey(), buffer); err != nil {
+			return err
+		}
+
+		if !better(node, c.state.node) {
+			// Side branch: indexed and persisted, but it doesn't unseat the current tip.
+			return nil
+		}
+
+		if err = c.reorganize(node); err != nil {
+			return err
+		}
+
+		buffer, err = storage.DefaultCodec.Encode(c.state.marshal)
+
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(metaBucket[:]).Put(metaStateKey, buffer)
+	}); err != nil {
+		return err
+	}
+
+	if node != c.state.node {
+		// The block was indexed and persisted but didn't unseat the current tip.
+		c.sideFeed.Send(ChainSideEvent{Block: node.asBlock()})
+	}
+
+	// A previously orphaned block may now extend this one: re-evaluate them. Failures there must
+	// not fail this call, whose own block has already been accepted.
+	for _, orphan := range c.index.popOrphans(blockHash) {
+		_ = c.AdviseNewBlock(orphan)
+	}
+
+	return nil
 }
 
 // IsMyTurn returns whether it's my turn to produce block or not.
 //
-// TODO(leventliu): need implementation.
+// TODO(leventliu): need implementation of the real multi-producer turn schedule.
 func (c *Chain) IsMyTurn() bool {
+	if c.cfg.DevMode {
+		return true
+	}
+
 	return false
 }
 
+// ProduceBlock signs the pending block, pushes it to extend the local chain, and broadcasts it
+// to the rest of the producers, if a broadcast function has been wired up.
 func (c *Chain) ProduceBlock() (err error) {
 	// TODO(leventeliu): remember to initialize local key store somewhere.
 	priv, err := kms.GetLocalPrivateKey()
@@ -362,19 +474,75 @@ func (c *Chain) ProduceBlock() (err error) {
 		return
 	}
 
+	produced := c.pendingBlock
+	c.pendingBlock = &Block{}
+
+	c.pendingMu.Lock()
+	c.pendingTxs = nil
+	c.pendingMu.Unlock()
+
+	if produced.SignedHeader == nil {
+		// Nothing queued it up with transactions: produce an empty block extending the current
+		// tip, same as the tx pool would have started one.
+		produced.SignedHeader = &SignedHeader{}
+	}
+
+	produced.SignedHeader.Header.ParentHash = c.state.Head
+	produced.SignedHeader.Timestamp = c.rt.Now()
+
 	// Sign pending block
-	err = c.pendingBlock.SignHeader(priv)
+	if err = produced.SignHeader(priv); err != nil {
+		return
+	}
 
-	if err != nil {
+	if err = c.PushBlock(produced.SignedHeader); err != nil {
 		return
 	}
 
-	// TODO(leventeliu): advise new block
+	if c.broadcast != nil {
+		err = c.broadcast(produced)
+	}
 
 	return
 }
 
+// Commit forces an immediate call to ProduceBlock, bypassing the turn schedule entirely. It
+// exists for dev mode and tests, where a block should be produced on demand rather than waiting
+// for a timer.
+func (c *Chain) Commit() (err error) {
+	return c.ProduceBlock()
+}
+
+// SetBroadcastFunc installs the function used to announce a newly produced block to the rest of
+// the producers. Without one, ProduceBlock only extends the local chain.
+func (c *Chain) SetBroadcastFunc(broadcast func(*Block) error) {
+	c.broadcast = broadcast
+}
+
+// AddPendingTx enqueues tx into the pending pool and fires a NewPendingTxEvent.
+//
+// TODO(leventeliu): this is a minimal stand-in for the real tx pool; it doesn't validate tx, dedup
+// it, or feed it into the block a producer actually signs.
+func (c *Chain) AddPendingTx(tx *Tx) {
+	c.pendingMu.Lock()
+	c.pendingTxs = append(c.pendingTxs, tx)
+	c.pendingMu.Unlock()
+
+	c.newTxFeed.Send(NewPendingTxEvent{Tx: tx})
+}
+
+// hasPendingQueries reports whether there is a pending tx worth producing a block for.
+func (c *Chain) hasPendingQueries() bool {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	return len(c.pendingTxs) > 0
+}
+
 func (c *Chain) Cycle() {
+	if c.cfg.DevMode && c.cfg.DevNoEmpty && !c.hasPendingQueries() {
+		return
+	}
+
 	if err := c.ProduceBlock(); err != nil {
 		c.Stop()
 	}
@@ -386,6 +554,18 @@ func (c *Chain) BlockProducingCycle() {
 		case <-c.rt.stopCh:
 			return
 		default:
+			if c.cfg.DevMode && c.cfg.DevPeriod == 0 {
+				// On-demand dev mode: auto-produce once a pending tx shows up, polled at
+				// devIdlePoll instead of waiting on a fixed period. Commit() can still force a
+				// block immediately regardless of what this poll sees.
+				if c.hasPendingQueries() {
+					c.Cycle()
+				}
+
+				time.Sleep(devIdlePoll)
+				continue
+			}
+
 			if d := c.rt.TillNextWakeUp(); d > 0 {
 				time.Sleep(d)
 			} else if c.IsMyTurn() {
@@ -0,0 +1,128 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlchain
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/thunderdb/ThunderDB/crypto/kms"
+	"gitlab.com/thunderdb/ThunderDB/sqlchain/storage"
+)
+
+// TestCommitProducesBlocksWithIncreasingHeights checks that N calls to Commit(), the on-demand
+// dev-mode entry point, extend the chain by exactly N blocks with monotonically increasing
+// heights, against a Chain backed by storage.OpenMemory (no filesystem involved).
+func TestCommitProducesBlocksWithIncreasingHeights(t *testing.T) {
+	priv, err := kms.GetLocalPrivateKey()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genesisHeader := &SignedHeader{Timestamp: time.Now()}
+	genesisHeader.Header.ParentHash = genesisHeader.RootHash
+	genesis := &Block{SignedHeader: genesisHeader}
+
+	if err := genesis.SignHeader(priv); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		Genesis: genesis,
+		DevMode: true,
+		Store:   storage.OpenMemory(),
+	}
+
+	chain, err := NewChain(cfg)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 3
+	lastHeight := chain.state.Height
+
+	for i := 0; i < n; i++ {
+		if err := chain.Commit(); err != nil {
+			t.Fatalf("Commit() #%d: %v", i, err)
+		}
+
+		if chain.state.Height != lastHeight+1 {
+			t.Fatalf("expected height to increase by 1 on Commit() #%d, went from %d to %d",
+				i, lastHeight, chain.state.Height)
+		}
+
+		lastHeight = chain.state.Height
+	}
+
+	if chain.state.Height != int32(n) {
+		t.Fatalf("expected %d blocks to have been committed, final height is %d", n, chain.state.Height)
+	}
+}
+
+// TestBlockProducingCycleProducesOnPendingTx checks on-demand dev mode's actual promise: with
+// DevPeriod == 0, BlockProducingCycle produces a block on its own once a tx is pending, without
+// anyone calling Commit().
+func TestBlockProducingCycleProducesOnPendingTx(t *testing.T) {
+	priv, err := kms.GetLocalPrivateKey()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genesisHeader := &SignedHeader{Timestamp: time.Now()}
+	genesisHeader.Header.ParentHash = genesisHeader.RootHash
+	genesis := &Block{SignedHeader: genesisHeader}
+
+	if err := genesis.SignHeader(priv); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		Genesis: genesis,
+		DevMode: true,
+		Store:   storage.OpenMemory(),
+	}
+
+	chain, err := NewChain(cfg)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go chain.BlockProducingCycle()
+	defer chain.Stop()
+
+	if chain.hasPendingQueries() {
+		t.Fatal("expected no pending queries before AddPendingTx")
+	}
+
+	chain.AddPendingTx(&Tx{})
+
+	deadline := time.Now().Add(time.Second)
+
+	for time.Now().Before(deadline) {
+		if chain.Head().Height > 0 {
+			return
+		}
+
+		time.Sleep(devIdlePoll)
+	}
+
+	t.Fatal("expected BlockProducingCycle to produce a block once a tx became pending")
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlchain
+
+import (
+	"time"
+
+	"gitlab.com/thunderdb/ThunderDB/sqlchain/storage"
+)
+
+// Config holds everything needed to create or load a Chain.
+type Config struct {
+	// DataDir is the path to the chain's bbolt database file. Unused if Store is set.
+	DataDir string
+
+	// Genesis is the chain's genesis block.
+	Genesis *Block
+
+	// Period is the block producing cycle used by the real multi-producer turn schedule.
+	Period time.Duration
+
+	// DevMode, when set, makes IsMyTurn always return true and takes Period from DevPeriod
+	// instead, so a single node can produce its own blocks without a turn schedule. It exists
+	// for local development and integration testing, never for production use.
+	DevMode bool
+
+	// DevPeriod is the block period used in dev mode. Zero means a block is produced only when
+	// there is at least one pending transaction, rather than on a fixed cycle.
+	DevPeriod time.Duration
+
+	// DevNoEmpty, when set alongside DevMode, skips producing a block for a cycle that has no
+	// pending transactions even if DevPeriod is non-zero.
+	DevNoEmpty bool
+
+	// Store is the KV the chain persists to. If nil, NewChain and LoadChain open a bbolt
+	// database at DataDir instead. Tests set this to storage.OpenMemory() to exercise a Chain
+	// without touching the filesystem.
+	Store storage.KV
+}
+
+// store returns the Config's Store, opening a bbolt database at DataDir if none was supplied.
+func (c *Config) store() (storage.KV, error) {
+	if c.Store != nil {
+		return c.Store, nil
+	}
+
+	return storage.OpenBolt(c.DataDir)
+}
+
+// period returns the block producing cycle the Runtime should use, honoring DevMode.
+func (c *Config) period() time.Duration {
+	if c.DevMode {
+		return c.DevPeriod
+	}
+
+	return c.Period
+}
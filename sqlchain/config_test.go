@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlchain
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/thunderdb/ThunderDB/sqlchain/storage"
+)
+
+func TestConfigPeriodHonorsDevMode(t *testing.T) {
+	cfg := &Config{Period: 10 * time.Second}
+
+	if got := cfg.period(); got != 10*time.Second {
+		t.Fatalf("expected the real period when not in dev mode, got %s", got)
+	}
+
+	cfg.DevMode = true
+	cfg.DevPeriod = 250 * time.Millisecond
+
+	if got := cfg.period(); got != 250*time.Millisecond {
+		t.Fatalf("expected DevPeriod in dev mode, got %s", got)
+	}
+}
+
+func TestConfigStorePrefersExplicitStore(t *testing.T) {
+	mem := storage.OpenMemory()
+	cfg := &Config{Store: mem}
+
+	got, err := cfg.store()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != mem {
+		t.Fatal("expected store() to return the explicitly configured Store")
+	}
+}
+
+func TestIsMyTurnOnlyInDevMode(t *testing.T) {
+	chain := &Chain{cfg: &Config{}}
+
+	if chain.IsMyTurn() {
+		t.Fatal("did not expect IsMyTurn to be true outside dev mode")
+	}
+
+	chain.cfg.DevMode = true
+
+	if !chain.IsMyTurn() {
+		t.Fatal("expected IsMyTurn to always be true in dev mode")
+	}
+}
+
+func TestCycleSkipsEmptyBlocksWhenDevNoEmpty(t *testing.T) {
+	chain := &Chain{
+		cfg:          &Config{DevMode: true, DevNoEmpty: true},
+		rt:           &Runtime{stopCh: make(chan struct{})},
+		pendingBlock: &Block{},
+	}
+
+	chain.Cycle()
+
+	select {
+	case <-chain.rt.stopCh:
+		t.Fatal("did not expect Cycle to stop the chain for an empty pending block")
+	default:
+	}
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlchain
+
+import "errors"
+
+var (
+	// ErrInvalidBlock indicates that a block passed to PushBlock does not extend the current best
+	// chain. PushBlock does not try to accept such a block as a side-chain block; AdviseNewBlock is
+	// the path that indexes side-chain and orphan blocks.
+	ErrInvalidBlock = errors.New("sqlchain: invalid block")
+	// ErrParentNotFound indicates that a block's parent could not be located while rebuilding the
+	// in-memory index from disk.
+	ErrParentNotFound = errors.New("sqlchain: parent block not found")
+	// ErrBlockExists indicates that the advised block is already present in the index.
+	ErrBlockExists = errors.New("sqlchain: block already exists")
+	// ErrBlockTimestampOutOfPeriod indicates that the advised block was not produced within its
+	// expected turn.
+	ErrBlockTimestampOutOfPeriod = errors.New("sqlchain: block timestamp out of period")
+	// ErrOrphanBlock indicates that the advised block's parent is not yet known to the index. The
+	// block is cached in the orphan pool and will be re-evaluated once its parent arrives.
+	ErrOrphanBlock = errors.New("sqlchain: orphan block")
+	// ErrNoSuchBlock indicates that a lookup by hash or height did not match any known block.
+	ErrNoSuchBlock = errors.New("sqlchain: no such block")
+)
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlchain
+
+import "gitlab.com/thunderdb/ThunderDB/sqlchain/events"
+
+// ChainHeadEvent fires whenever a block is added to the best chain, whether by a plain extend or
+// as the final step of a reorg.
+type ChainHeadEvent struct {
+	Block *Block
+}
+
+// ChainSideEvent fires when a block is accepted into the index but does not (yet) extend the
+// best chain.
+type ChainSideEvent struct {
+	Block *Block
+}
+
+// ChainReorgEvent fires once per reorg, before the ChainHeadEvents for the newly connected
+// blocks. Old and New are ordered tip-to-fork and fork-to-tip respectively; Common is the fork
+// point itself.
+type ChainReorgEvent struct {
+	Old, New []*Block
+	Common   *Block
+}
+
+// NewPendingTxEvent fires when a transaction is accepted into the pending pool.
+type NewPendingTxEvent struct {
+	Tx *Tx
+}
+
+// RemovedTxEvent is the event for a previously pending transaction being dropped, e.g. because a
+// reorg invalidated the block it was mined into. No code path in Chain sends it: neither
+// ProduceBlock, which clears the pending pool, nor reorganize fires it, so a SubscribeRemovedTx
+// subscription receives no events.
+type RemovedTxEvent struct {
+	Tx *Tx
+}
+
+// SubscribeChainHead registers ch to receive ChainHeadEvents. The subscription is dropped if ch
+// is not drained quickly enough; see events.Feed.
+func (c *Chain) SubscribeChainHead(ch chan<- ChainHeadEvent) events.Subscription {
+	return c.headFeed.Subscribe(ch)
+}
+
+// SubscribeChainSide registers ch to receive ChainSideEvents.
+func (c *Chain) SubscribeChainSide(ch chan<- ChainSideEvent) events.Subscription {
+	return c.sideFeed.Subscribe(ch)
+}
+
+// SubscribeChainReorg registers ch to receive ChainReorgEvents.
+func (c *Chain) SubscribeChainReorg(ch chan<- ChainReorgEvent) events.Subscription {
+	return c.reorgFeed.Subscribe(ch)
+}
+
+// SubscribeNewPendingTx registers ch to receive NewPendingTxEvents.
+func (c *Chain) SubscribeNewPendingTx(ch chan<- NewPendingTxEvent) events.Subscription {
+	return c.newTxFeed.Subscribe(ch)
+}
+
+// SubscribeRemovedTx registers ch to receive RemovedTxEvents.
+func (c *Chain) SubscribeRemovedTx(ch chan<- RemovedTxEvent) events.Subscription {
+	return c.removedTxFeed.Subscribe(ch)
+}
+
+// asBlock returns the node's full block if AdviseNewBlock cached one, or a header-only Block
+// otherwise (e.g. for nodes rebuilt by LoadChain).
+func (n *blockNode) asBlock() *Block {
+	if n.block != nil {
+		return n.block
+	}
+
+	return &Block{SignedHeader: n.header}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package events provides a small, type-agnostic publish/subscribe primitive, similar in spirit
+// to go-ethereum's event package: a Feed broadcasts values of any one type to however many
+// channels are currently subscribed, and a slow subscriber is dropped instead of stalling the
+// sender. Domain-specific event types (ChainHeadEvent and friends) are defined in the sqlchain
+// package itself, which both produces and depends on them; keeping them out of this package is
+// what lets sqlchain import it without an import cycle.
+package events
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Subscription represents a subscription to a Feed.
+type Subscription interface {
+	// Unsubscribe cancels the subscription. It is safe to call more than once.
+	Unsubscribe()
+	// Err returns a channel that is closed when the subscription ends.
+	Err() <-chan error
+}
+
+// Feed broadcasts values of a single type to a set of subscribed channels. The zero value is a
+// usable Feed with no subscribers.
+type Feed struct {
+	mu   sync.Mutex
+	subs map[*feedSub]struct{}
+}
+
+type feedSub struct {
+	feed    *Feed
+	channel reflect.Value
+	err     chan error
+	once    sync.Once
+}
+
+// Subscribe adds channel, which must be a writable channel, to the feed's subscriber set.
+func (f *Feed) Subscribe(channel interface{}) Subscription {
+	chanVal := reflect.ValueOf(channel)
+
+	if chanVal.Kind() != reflect.Chan || chanVal.Type().ChanDir()&reflect.SendDir == 0 {
+		panic("events: Subscribe argument must be a writable channel")
+	}
+
+	sub := &feedSub{feed: f, channel: chanVal, err: make(chan error, 1)}
+
+	f.mu.Lock()
+	if f.subs == nil {
+		f.subs = make(map[*feedSub]struct{})
+	}
+	f.subs[sub] = struct{}{}
+	f.mu.Unlock()
+
+	return sub
+}
+
+// Send delivers event to every currently subscribed channel and returns how many received it.
+// Subscribers whose channel is full are unsubscribed and skipped rather than blocking the
+// sender — a block producer must never stall waiting on a slow reader.
+func (f *Feed) Send(event interface{}) (n int) {
+	f.mu.Lock()
+	subs := make([]*feedSub, 0, len(f.subs))
+	for sub := range f.subs {
+		subs = append(subs, sub)
+	}
+	f.mu.Unlock()
+
+	val := reflect.ValueOf(event)
+
+	for _, sub := range subs {
+		chosen, _, _ := reflect.Select([]reflect.SelectCase{
+			{Dir: reflect.SelectSend, Chan: sub.channel, Send: val},
+			{Dir: reflect.SelectDefault},
+		})
+
+		if chosen == 0 {
+			n++
+		} else {
+			sub.Unsubscribe()
+		}
+	}
+
+	return
+}
+
+func (s *feedSub) Unsubscribe() {
+	s.once.Do(func() {
+		s.feed.mu.Lock()
+		delete(s.feed.subs, s)
+		s.feed.mu.Unlock()
+		close(s.err)
+	})
+}
+
+func (s *feedSub) Err() <-chan error {
+	return s.err
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlchain
+
+import (
+	"time"
+
+	"gitlab.com/thunderdb/ThunderDB/crypto/hash"
+	"gitlab.com/thunderdb/ThunderDB/sqlchain/storage"
+)
+
+// BlockByHash returns the block with the given hash from any known branch, main or side.
+func (c *Chain) BlockByHash(h hash.Hash) (block *Block, err error) {
+	node := c.index.LookupNode(&h)
+
+	if node == nil {
+		return nil, ErrNoSuchBlock
+	}
+
+	return c.blockFromNode(node)
+}
+
+// BlockByHeight returns the best-chain block at height, or ErrNoSuchBlock if height is beyond the
+// current tip.
+func (c *Chain) BlockByHeight(height int32) (block *Block, err error) {
+	c.stateMu.RLock()
+	node := c.state.node.ancestor(height)
+	c.stateMu.RUnlock()
+
+	if node == nil {
+		return nil, ErrNoSuchBlock
+	}
+
+	return c.blockFromNode(node)
+}
+
+// HeaderByHeight returns the best-chain header at height, or ErrNoSuchBlock if height is beyond
+// the current tip.
+func (c *Chain) HeaderByHeight(height int32) (header *SignedHeader, err error) {
+	c.stateMu.RLock()
+	node := c.state.node.ancestor(height)
+	c.stateMu.RUnlock()
+
+	if node == nil {
+		return nil, ErrNoSuchBlock
+	}
+
+	return node.header, nil
+}
+
+// Head returns a snapshot of the current best-chain state.
+func (c *Chain) Head() State {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return *c.state
+}
+
+// Period returns the chain's block producing cycle, accounting for dev mode's DevPeriod override.
+func (c *Chain) Period() time.Duration {
+	return c.cfg.period()
+}
+
+// TillNextWakeUp wraps Runtime.TillNextWakeUp so that callers outside the package (notably
+// sqlchain/rpc) don't need access to the Runtime itself.
+func (c *Chain) TillNextWakeUp() time.Duration {
+	return c.rt.TillNextWakeUp()
+}
+
+// blockFromNode resolves node to a full Block, preferring the in-memory copy kept by
+// AdviseNewBlock and otherwise rebuilding a header-only Block from the store.
+func (c *Chain) blockFromNode(node *blockNode) (block *Block, err error) {
+	if node.block != nil {
+		return node.block, nil
+	}
+
+	header := &SignedHeader{}
+
+	err = c.db.View(func(tx storage.Tx) error {
+		buffer := tx.Bucket(metaBucket[:]).Bucket(metaBlockIndexBucket).Get(node.indexKey())
+
+		if buffer == nil {
+			return ErrNoSuchBlock
+		}
+
+		return storage.DefaultCodec.Decode(buffer, header.unmarshal)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Block{SignedHeader: header}, nil
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlchain
+
+// DisconnectBlockCallback is invoked, in tip-to-fork order, for every block that a reorg removes
+// from the best chain. Higher layers use it to roll back the SQL state and pending tx pool that
+// the block had applied.
+type DisconnectBlockCallback func(block *Block) error
+
+// ConnectBlockCallback is invoked, in fork-to-tip order, for every block that a reorg adds to the
+// best chain, mirroring DisconnectBlockCallback.
+type ConnectBlockCallback func(block *Block) error
+
+// RegisterDisconnectBlockCallback adds cb to the set of callbacks run when a block is detached
+// from the best chain during a reorg.
+func (c *Chain) RegisterDisconnectBlockCallback(cb DisconnectBlockCallback) {
+	c.disconnectCBs = append(c.disconnectCBs, cb)
+}
+
+// RegisterConnectBlockCallback adds cb to the set of callbacks run when a block is attached to
+// the best chain during a reorg.
+func (c *Chain) RegisterConnectBlockCallback(cb ConnectBlockCallback) {
+	c.connectCBs = append(c.connectCBs, cb)
+}
+
+// reorganize rewrites c.state so that it points at newTip, disconnecting every block between the
+// current tip and their common ancestor and connecting every block between that ancestor and
+// newTip. It does not touch bbolt; callers persist the resulting c.state themselves within the
+// same transaction that reorganize was called under.
+func (c *Chain) reorganize(newTip *blockNode) error {
+	fork := findFork(c.state.node, newTip)
+
+	var oldBlocks, newBlocks []*Block
+
+	for n := c.state.node; n != fork; n = n.parent {
+		for _, cb := range c.disconnectCBs {
+			if err := cb(n.asBlock()); err != nil {
+				return err
+			}
+		}
+
+		oldBlocks = append(oldBlocks, n.asBlock())
+	}
+
+	var attach []*blockNode
+
+	for n := newTip; n != fork; n = n.parent {
+		attach = append(attach, n)
+	}
+
+	for i := len(attach) - 1; i >= 0; i-- {
+		for _, cb := range c.connectCBs {
+			if err := cb(attach[i].asBlock()); err != nil {
+				return err
+			}
+		}
+
+		newBlocks = append(newBlocks, attach[i].asBlock())
+	}
+
+	c.stateMu.Lock()
+	c.state.node = newTip
+	c.state.Head = newTip.hash
+	c.state.Height = newTip.height
+	c.stateMu.Unlock()
+
+	// fork == c.state.node's old value means newTip is a plain linear extension of the current
+	// tip, not a genuine fork switch: there's nothing to disconnect, so don't spam a
+	// ChainReorgEvent for every ordinary block AdviseNewBlock accepts.
+	if len(oldBlocks) > 0 {
+		c.reorgFeed.Send(ChainReorgEvent{Old: oldBlocks, New: newBlocks, Common: fork.asBlock()})
+	}
+
+	for _, b := range newBlocks {
+		c.headFeed.Send(ChainHeadEvent{Block: b})
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlchain
+
+import "testing"
+
+func TestReorgFiresReorgThenHeadEventsInOrder(t *testing.T) {
+	genesis := &blockNode{height: 0, workSum: 1}
+	oldTip := chainFrom(genesis, 2, 0x01)
+	newTip := chainFrom(genesis, 3, 0x02)
+
+	// Give each node of the new branch its own block, in fork-to-tip order, so the ChainHeadEvents
+	// can be matched against it.
+	var wantBlocks []*Block
+	for n := newTip; n != genesis; n = n.parent {
+		n.block = &Block{}
+		wantBlocks = append([]*Block{n.block}, wantBlocks...)
+	}
+
+	chain := &Chain{state: &State{node: oldTip, Head: oldTip.hash, Height: oldTip.height}}
+
+	reorgCh := make(chan ChainReorgEvent, 1)
+	headCh := make(chan ChainHeadEvent, 3)
+	chain.SubscribeChainReorg(reorgCh)
+	chain.SubscribeChainHead(headCh)
+
+	if err := chain.reorganize(newTip); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-reorgCh:
+		if len(ev.Old) != 2 || len(ev.New) != 3 {
+			t.Fatalf("expected 2 disconnected and 3 connected blocks, got %d/%d",
+				len(ev.Old), len(ev.New))
+		}
+	default:
+		t.Fatal("expected a ChainReorgEvent")
+	}
+
+	wantHeights := []int32{1, 2, 3}
+
+	for i, wantHeight := range wantHeights {
+		select {
+		case ev := <-headCh:
+			if ev.Block == nil {
+				t.Fatal("expected a non-nil block on the ChainHeadEvent")
+			}
+
+			if ev.Block != wantBlocks[i] {
+				t.Fatalf("expected the ChainHeadEvent for height %d in fork-to-tip order", wantHeight)
+			}
+		default:
+			t.Fatalf("expected a ChainHeadEvent for height %d", wantHeight)
+		}
+	}
+
+	if chain.state.node != newTip {
+		t.Fatal("expected state to point at the new tip after reorganize")
+	}
+}
+
+func TestReorgDoesNotFireReorgEventForLinearExtension(t *testing.T) {
+	genesis := &blockNode{height: 0, workSum: 1}
+	tip := chainFrom(genesis, 2, 0x01)
+	next := chainFrom(tip, 1, 0x02)
+
+	chain := &Chain{state: &State{node: tip, Head: tip.hash, Height: tip.height}}
+
+	reorgCh := make(chan ChainReorgEvent, 1)
+	headCh := make(chan ChainHeadEvent, 1)
+	chain.SubscribeChainReorg(reorgCh)
+	chain.SubscribeChainHead(headCh)
+
+	if err := chain.reorganize(next); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-reorgCh:
+		t.Fatalf("expected no ChainReorgEvent for a plain linear extension, got %+v", ev)
+	default:
+	}
+
+	select {
+	case <-headCh:
+	default:
+		t.Fatal("expected a ChainHeadEvent for the extended block")
+	}
+
+	if chain.state.node != next {
+		t.Fatal("expected state to point at the extended tip after reorganize")
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Client is a minimal JSON-RPC 2.0 client for the HTTP Handler, used by the `covenantsql attach`
+// CLI mode and by tests that boot a chain and query it without going through the real transport.
+type Client struct {
+	addr string
+	http *http.Client
+}
+
+// NewClient creates a Client that talks to the Handler listening at addr.
+func NewClient(addr string) *Client {
+	return &Client{addr: addr, http: http.DefaultClient}
+}
+
+// Call invokes method with params and decodes the result into out, if out is non-nil.
+func (c *Client) Call(out interface{}, method string, params ...interface{}) error {
+	rawParams := make([]json.RawMessage, len(params))
+
+	for i, p := range params {
+		raw, err := json.Marshal(p)
+
+		if err != nil {
+			return err
+		}
+
+		rawParams[i] = raw
+	}
+
+	body, err := json.Marshal(Request{Version: "2.0", Method: method, Params: rawParams})
+
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Post(c.addr, "application/json", bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	var rpcResp Response
+
+	if err = json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return err
+	}
+
+	if rpcResp.Error != nil {
+		return fmt.Errorf("rpc: %s", rpcResp.Error.Message)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(rpcResp.Result)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, out)
+}
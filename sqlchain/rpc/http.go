@@ -0,0 +1,132 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// Request is a JSON-RPC 2.0 request object.
+type Request struct {
+	Version string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id,omitempty"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC 2.0 response object.
+type Response struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *ResponseError  `json:"error,omitempty"`
+}
+
+// ResponseError is a JSON-RPC 2.0 error object.
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Handler serves a Service's methods as JSON-RPC 2.0 over HTTP, one call per request. It does not
+// attempt to support the existing length-prefixed RPC transport's batching or streaming; it is
+// meant for operators and light clients reaching in over plain HTTP.
+type Handler struct {
+	methods map[string]reflect.Value
+}
+
+// NewHandler builds a Handler for the given Service.
+func NewHandler(s *Service) *Handler {
+	methods := make(map[string]reflect.Value, len(s.Methods()))
+
+	for name, fn := range s.Methods() {
+		methods[name] = reflect.ValueOf(fn)
+	}
+
+	return &Handler{methods: methods}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req Request
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, nil, fmt.Errorf("rpc: malformed request: %w", err))
+		return
+	}
+
+	result, err := h.call(req.Method, req.Params)
+
+	if err != nil {
+		writeError(w, req.ID, err)
+		return
+	}
+
+	writeResult(w, req.ID, result)
+}
+
+func (h *Handler) call(method string, rawParams []json.RawMessage) (interface{}, error) {
+	fn, ok := h.methods[method]
+
+	if !ok {
+		return nil, fmt.Errorf("rpc: unknown method %q", method)
+	}
+
+	fnType := fn.Type()
+
+	if len(rawParams) != fnType.NumIn() {
+		return nil, fmt.Errorf("rpc: method %q expects %d params, got %d",
+			method, fnType.NumIn(), len(rawParams))
+	}
+
+	args := make([]reflect.Value, fnType.NumIn())
+
+	for i, raw := range rawParams {
+		arg := reflect.New(fnType.In(i))
+
+		if err := json.Unmarshal(raw, arg.Interface()); err != nil {
+			return nil, fmt.Errorf("rpc: param %d: %w", i, err)
+		}
+
+		args[i] = arg.Elem()
+	}
+
+	out := fn.Call(args)
+
+	if errVal := out[len(out)-1]; !errVal.IsNil() {
+		return nil, errVal.Interface().(error)
+	}
+
+	return out[0].Interface(), nil
+}
+
+func writeResult(w http.ResponseWriter, id json.RawMessage, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Version: "2.0", ID: id, Result: result})
+}
+
+func writeError(w http.ResponseWriter, id json.RawMessage, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{
+		Version: "2.0",
+		ID:      id,
+		Error:   &ResponseError{Code: -32000, Message: err.Error()},
+	})
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package rpc exposes a running sqlchain.Chain over JSON-RPC 2.0, modelled on the eth_/admin_
+// namespace split used by Ethereum clients: chain_* methods are read-only and safe to expose to
+// any client, admin_* methods control the local node and should only be reachable by operators.
+package rpc
+
+import (
+	"time"
+
+	"gitlab.com/thunderdb/ThunderDB/crypto/hash"
+	"gitlab.com/thunderdb/ThunderDB/sqlchain"
+)
+
+// ChainReader is the read-only retrieval API that both the RPC service and future light clients
+// can share, rather than each poking at blockIndex and bbolt directly.
+type ChainReader interface {
+	BlockByHash(h hash.Hash) (*sqlchain.Block, error)
+	BlockByHeight(height int32) (*sqlchain.Block, error)
+	HeaderByHeight(height int32) (*sqlchain.SignedHeader, error)
+}
+
+// Backend is everything the Service needs from a running chain: the ChainReader retrieval API,
+// plus the bits of state and control that don't fit a pure read model.
+type Backend interface {
+	ChainReader
+
+	Head() sqlchain.State
+	Period() time.Duration
+	TillNextWakeUp() time.Duration
+	Stop()
+}
+
+// NodeInfo is the payload returned by admin_nodeInfo.
+type NodeInfo struct {
+	Head   hash.Hash `json:"head"`
+	Height int32     `json:"height"`
+	Period string    `json:"period"`
+}
+
+// Service implements the chain_* and admin_* JSON-RPC methods against a Backend.
+type Service struct {
+	backend Backend
+}
+
+// NewService creates a Service backed by the given chain.
+func NewService(backend Backend) *Service {
+	return &Service{backend: backend}
+}
+
+// ChainBlockByHeight implements chain_blockByHeight.
+func (s *Service) ChainBlockByHeight(height int32) (*sqlchain.Block, error) {
+	return s.backend.BlockByHeight(height)
+}
+
+// ChainBlockByHash implements chain_blockByHash.
+func (s *Service) ChainBlockByHash(h hash.Hash) (*sqlchain.Block, error) {
+	return s.backend.BlockByHash(h)
+}
+
+// ChainHeaderByHeight implements chain_headerByHeight.
+func (s *Service) ChainHeaderByHeight(height int32) (*sqlchain.SignedHeader, error) {
+	return s.backend.HeaderByHeight(height)
+}
+
+// ChainHead implements chain_head.
+func (s *Service) ChainHead() (sqlchain.State, error) {
+	return s.backend.Head(), nil
+}
+
+// ChainNextTurnETA implements chain_nextTurnETA.
+func (s *Service) ChainNextTurnETA() (time.Duration, error) {
+	return s.backend.TillNextWakeUp(), nil
+}
+
+// ChainPeriod implements chain_period.
+func (s *Service) ChainPeriod() (time.Duration, error) {
+	return s.backend.Period(), nil
+}
+
+// ChainProducers implements chain_producers.
+//
+// TODO(leventeliu): there is no producer roster to report yet; wire this up once peer membership
+// lands.
+func (s *Service) ChainProducers() ([]string, error) {
+	return nil, nil
+}
+
+// AdminStop implements admin_stop.
+func (s *Service) AdminStop() (bool, error) {
+	s.backend.Stop()
+	return true, nil
+}
+
+// AdminAddPeer implements admin_addPeer.
+//
+// TODO(leventeliu): there is no peer manager to register addr with yet.
+func (s *Service) AdminAddPeer(addr string) (bool, error) {
+	return false, nil
+}
+
+// AdminNodeInfo implements admin_nodeInfo.
+func (s *Service) AdminNodeInfo() (NodeInfo, error) {
+	head := s.backend.Head()
+	return NodeInfo{
+		Head:   head.Head,
+		Height: head.Height,
+		Period: s.backend.Period().String(),
+	}, nil
+}
+
+// Methods returns the dispatch table used to register the service on the RPC transport, keyed by
+// the JSON-RPC method name.
+func (s *Service) Methods() map[string]interface{} {
+	return map[string]interface{}{
+		"chain_blockByHeight":  s.ChainBlockByHeight,
+		"chain_blockByHash":    s.ChainBlockByHash,
+		"chain_headerByHeight": s.ChainHeaderByHeight,
+		"chain_head":           s.ChainHead,
+		"chain_nextTurnETA":    s.ChainNextTurnETA,
+		"chain_period":         s.ChainPeriod,
+		"chain_producers":      s.ChainProducers,
+		"admin_stop":           s.AdminStop,
+		"admin_addPeer":        s.AdminAddPeer,
+		"admin_nodeInfo":       s.AdminNodeInfo,
+	}
+}
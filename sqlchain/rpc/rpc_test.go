@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gitlab.com/thunderdb/ThunderDB/crypto/hash"
+	"gitlab.com/thunderdb/ThunderDB/sqlchain"
+)
+
+// fakeBackend is a minimal in-memory Backend used to exercise the RPC layer without booting a
+// real bbolt-backed Chain, which needs a signed genesis block to construct.
+type fakeBackend struct {
+	height int32
+	period time.Duration
+}
+
+func (b *fakeBackend) BlockByHash(h hash.Hash) (*sqlchain.Block, error) {
+	return &sqlchain.Block{}, nil
+}
+
+func (b *fakeBackend) BlockByHeight(height int32) (*sqlchain.Block, error) {
+	return &sqlchain.Block{}, nil
+}
+
+func (b *fakeBackend) HeaderByHeight(height int32) (*sqlchain.SignedHeader, error) {
+	return &sqlchain.SignedHeader{}, nil
+}
+
+func (b *fakeBackend) Head() sqlchain.State {
+	return sqlchain.State{Height: b.height}
+}
+
+func (b *fakeBackend) Period() time.Duration {
+	return b.period
+}
+
+func (b *fakeBackend) TillNextWakeUp() time.Duration {
+	return b.period
+}
+
+func (b *fakeBackend) Stop() {}
+
+// TestTwoChainsOverHTTP boots two independent RPC services, each fronting its own chain state,
+// and checks that querying one does not see the other's.
+func TestTwoChainsOverHTTP(t *testing.T) {
+	chainA := &fakeBackend{height: 3, period: time.Second}
+	chainB := &fakeBackend{height: 7, period: 2 * time.Second}
+
+	serverA := httptest.NewServer(NewHandler(NewService(chainA)))
+	defer serverA.Close()
+	serverB := httptest.NewServer(NewHandler(NewService(chainB)))
+	defer serverB.Close()
+
+	clientA := NewClient(serverA.URL)
+	clientB := NewClient(serverB.URL)
+
+	var headA, headB sqlchain.State
+
+	if err := clientA.Call(&headA, "chain_head"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := clientB.Call(&headB, "chain_head"); err != nil {
+		t.Fatal(err)
+	}
+
+	if headA.Height != 3 {
+		t.Fatalf("expected chain A height 3, got %d", headA.Height)
+	}
+
+	if headB.Height != 7 {
+		t.Fatalf("expected chain B height 7, got %d", headB.Height)
+	}
+
+	var info NodeInfo
+
+	if err := clientA.Call(&info, "admin_nodeInfo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Period != time.Second.String() {
+		t.Fatalf("expected period %s, got %s", time.Second, info.Period)
+	}
+}
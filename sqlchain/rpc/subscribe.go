@@ -0,0 +1,124 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gitlab.com/thunderdb/ThunderDB/sqlchain"
+	"gitlab.com/thunderdb/ThunderDB/sqlchain/events"
+)
+
+// SubscribeBackend is the subset of Chain that chain_subscribe needs: the retrieval/control API
+// of Backend, plus the event feeds to stream from.
+type SubscribeBackend interface {
+	Backend
+
+	SubscribeChainHead(ch chan<- sqlchain.ChainHeadEvent) events.Subscription
+	SubscribeChainReorg(ch chan<- sqlchain.ChainReorgEvent) events.Subscription
+	SubscribeNewPendingTx(ch chan<- sqlchain.NewPendingTxEvent) events.Subscription
+}
+
+// SubscriptionHandler implements chain_subscribe over plain HTTP: a GET request with
+// ?topic=newHeads|reorgs|pendingTransactions keeps the connection open and writes one JSON
+// object per line as events arrive, until the client disconnects. It is the only chain_subscribe
+// implementation: Service.Methods has no chain_subscribe entry, and nothing registers one on the
+// length-prefixed RPC transport.
+type SubscriptionHandler struct {
+	backend SubscribeBackend
+}
+
+// NewSubscriptionHandler builds a SubscriptionHandler for the given chain.
+func NewSubscriptionHandler(backend SubscribeBackend) *SubscriptionHandler {
+	return &SubscriptionHandler{backend: backend}
+}
+
+func (h *SubscriptionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	w.Header().Set("Content-Type", "application/json")
+	ctx := r.Context()
+
+	switch topic := r.URL.Query().Get("topic"); topic {
+	case "newHeads":
+		ch := make(chan sqlchain.ChainHeadEvent, 16)
+		sub := h.backend.SubscribeChainHead(ch)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-ch:
+				if !writeEvent(encoder, flusher, event) {
+					return
+				}
+			}
+		}
+	case "reorgs":
+		ch := make(chan sqlchain.ChainReorgEvent, 16)
+		sub := h.backend.SubscribeChainReorg(ch)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-ch:
+				if !writeEvent(encoder, flusher, event) {
+					return
+				}
+			}
+		}
+	case "pendingTransactions":
+		ch := make(chan sqlchain.NewPendingTxEvent, 16)
+		sub := h.backend.SubscribeNewPendingTx(ch)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-ch:
+				if !writeEvent(encoder, flusher, event) {
+					return
+				}
+			}
+		}
+	default:
+		http.Error(w, fmt.Sprintf("rpc: unknown subscription topic %q", topic), http.StatusBadRequest)
+	}
+}
+
+// writeEvent encodes and flushes a single streamed event, reporting whether the connection is
+// still usable.
+func writeEvent(encoder *json.Encoder, flusher http.Flusher, event interface{}) bool {
+	if err := encoder.Encode(event); err != nil {
+		return false
+	}
+
+	flusher.Flush()
+	return true
+}
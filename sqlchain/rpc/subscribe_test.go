@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gitlab.com/thunderdb/ThunderDB/sqlchain"
+	"gitlab.com/thunderdb/ThunderDB/sqlchain/events"
+)
+
+// subscribeFakeBackend extends fakeBackend with real event feeds so the subscription handler has
+// something to stream from.
+type subscribeFakeBackend struct {
+	fakeBackend
+	headFeed events.Feed
+}
+
+func (b *subscribeFakeBackend) SubscribeChainHead(ch chan<- sqlchain.ChainHeadEvent) events.Subscription {
+	return b.headFeed.Subscribe(ch)
+}
+
+func (b *subscribeFakeBackend) SubscribeChainReorg(ch chan<- sqlchain.ChainReorgEvent) events.Subscription {
+	var f events.Feed
+	return f.Subscribe(ch)
+}
+
+func (b *subscribeFakeBackend) SubscribeNewPendingTx(ch chan<- sqlchain.NewPendingTxEvent) events.Subscription {
+	var f events.Feed
+	return f.Subscribe(ch)
+}
+
+func TestSubscriptionHandlerStreamsChainHeadEvents(t *testing.T) {
+	backend := &subscribeFakeBackend{fakeBackend: fakeBackend{height: 1, period: time.Second}}
+	server := httptest.NewServer(NewSubscriptionHandler(backend))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?topic=newHeads")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	// Give the handler a moment to register its subscription before we publish.
+	time.Sleep(10 * time.Millisecond)
+	backend.headFeed.Send(sqlchain.ChainHeadEvent{Block: &sqlchain.Block{}})
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if line == "" {
+		t.Fatal("expected a streamed event line")
+	}
+}
+
+// TestSubscriptionHandlerUnsubscribesOnClientDisconnect guards against the handler parking in a
+// blocking channel receive forever once a subscriber goes idle or disconnects: ctx.Done() and the
+// event channel must be arms of the same select, not checked only between blocking receives.
+func TestSubscriptionHandlerUnsubscribesOnClientDisconnect(t *testing.T) {
+	backend := &subscribeFakeBackend{fakeBackend: fakeBackend{height: 1, period: time.Second}}
+	server := httptest.NewServer(NewSubscriptionHandler(backend))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?topic=newHeads", nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Give the handler a moment to register its subscription, then disconnect.
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	resp.Body.Close()
+
+	deadline := time.Now().Add(time.Second)
+
+	for time.Now().Before(deadline) {
+		if n := backend.headFeed.Send(sqlchain.ChainHeadEvent{Block: &sqlchain.Block{}}); n == 0 {
+			return
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatal("expected the handler to unsubscribe once the client disconnected")
+}
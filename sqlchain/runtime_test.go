@@ -0,0 +1,34 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlchain
+
+import "testing"
+
+// TestRuntimeStopIsIdempotent guards against a panic (close of a closed channel) if Stop is ever
+// called twice, which admin_stop makes trivial for any unauthenticated RPC client to trigger.
+func TestRuntimeStopIsIdempotent(t *testing.T) {
+	rt := &Runtime{stopCh: make(chan struct{})}
+
+	rt.Stop()
+	rt.Stop()
+
+	select {
+	case <-rt.stopCh:
+	default:
+		t.Fatal("expected stopCh to be closed after Stop")
+	}
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+
+	badger "github.com/dgraph-io/badger/v2"
+)
+
+// bucketSep separates a bucket path from the key within it. Unlike bbolt, badger has no native
+// concept of nested buckets, so OpenBadger encodes a bucket as a key prefix instead.
+const bucketSep = 0x00
+
+// OpenBadger opens (creating if necessary) a badger-backed KV at path. It exists for deployments
+// that need higher write throughput than bbolt's single-writer B+tree gives them; the bucket
+// semantics Chain relies on (Get/Put/Cursor/CreateBucketIfNotExists) are emulated with key
+// prefixes rather than native support. Because a nested bucket's keys share its parent's prefix,
+// a Cursor over a bucket also yields the sentinel key and entries of every bucket nested inside
+// it, not only the bucket's own key/value pairs.
+func OpenBadger(path string) (KV, error) {
+	db, err := badger.Open(badger.DefaultOptions(path))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &badgerKV{db: db}, nil
+}
+
+type badgerKV struct {
+	db *badger.DB
+}
+
+func (k *badgerKV) Update(fn func(Tx) error) error {
+	return k.db.Update(func(txn *badger.Txn) error {
+		iters := new(openIterators)
+		defer iters.closeAll()
+		return fn(&badgerBucket{txn: txn, iters: iters})
+	})
+}
+
+func (k *badgerKV) View(fn func(Tx) error) error {
+	return k.db.View(func(txn *badger.Txn) error {
+		iters := new(openIterators)
+		defer iters.closeAll()
+		return fn(&badgerBucket{txn: txn, iters: iters})
+	})
+}
+
+func (k *badgerKV) Close() error {
+	return k.db.Close()
+}
+
+// openIterators collects every iterator opened by Cursor() within a single transaction, so
+// Update/View can close them all before the transaction is discarded. Badger panics if a
+// transaction is discarded (or committed) while one of its iterators is still open.
+type openIterators struct {
+	its []*badger.Iterator
+}
+
+func (o *openIterators) add(it *badger.Iterator) {
+	o.its = append(o.its, it)
+}
+
+func (o *openIterators) closeAll() {
+	for _, it := range o.its {
+		it.Close()
+	}
+}
+
+// badgerBucket plays the role of both Tx and Bucket: in a flat keyspace, "the root" and "a
+// bucket with a prefix" are the same kind of thing.
+type badgerBucket struct {
+	txn    *badger.Txn
+	prefix []byte
+	iters  *openIterators
+}
+
+// Bucket reports a nested bucket as existing only once its sentinel key (its own prefix) has
+// actually been written by CreateBucketIfNotExists; a prefix nobody has written to yet may still
+// have no keys under it, so a plain prefix scan can't tell "empty bucket" from "no such bucket".
+func (b *badgerBucket) Bucket(name []byte) Bucket {
+	child := &badgerBucket{txn: b.txn, prefix: appendBucketName(b.prefix, name), iters: b.iters}
+
+	if _, err := b.txn.Get(child.prefix); err != nil {
+		return nil
+	}
+
+	return child
+}
+
+func (b *badgerBucket) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	child := &badgerBucket{txn: b.txn, prefix: appendBucketName(b.prefix, name), iters: b.iters}
+
+	if _, err := b.txn.Get(child.prefix); err == badger.ErrKeyNotFound {
+		if err := b.txn.Set(child.prefix, []byte{}); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return child, nil
+}
+
+func (b *badgerBucket) Get(key []byte) []byte {
+	item, err := b.txn.Get(append(append([]byte{}, b.prefix...), key...))
+
+	if err != nil {
+		return nil
+	}
+
+	val, err := item.ValueCopy(nil)
+
+	if err != nil {
+		return nil
+	}
+
+	return val
+}
+
+func (b *badgerBucket) Put(key, value []byte) error {
+	return b.txn.Set(append(append([]byte{}, b.prefix...), key...), value)
+}
+
+func (b *badgerBucket) Cursor() Cursor {
+	it := b.txn.NewIterator(badger.DefaultIteratorOptions)
+	b.iters.add(it)
+	return &badgerCursor{it: it, prefix: b.prefix}
+}
+
+func appendBucketName(prefix, name []byte) []byte {
+	out := make([]byte, 0, len(prefix)+len(name)+1)
+	out = append(out, prefix...)
+	out = append(out, name...)
+	out = append(out, bucketSep)
+	return out
+}
+
+type badgerCursor struct {
+	it     *badger.Iterator
+	prefix []byte
+}
+
+func (c *badgerCursor) First() ([]byte, []byte) {
+	c.it.Seek(c.prefix)
+	return c.current()
+}
+
+func (c *badgerCursor) Next() ([]byte, []byte) {
+	c.it.Next()
+	return c.current()
+}
+
+// current returns the key/value pair the iterator is parked on, skipping the bucket's own
+// sentinel key (written by CreateBucketIfNotExists at exactly c.prefix) so that it never shows up
+// as a zero-length key alongside the bucket's real entries.
+func (c *badgerCursor) current() ([]byte, []byte) {
+	for c.it.ValidForPrefix(c.prefix) {
+		item := c.it.Item()
+		key := bytes.TrimPrefix(item.KeyCopy(nil), c.prefix)
+
+		if len(key) == 0 {
+			c.it.Next()
+			continue
+		}
+
+		val, err := item.ValueCopy(nil)
+
+		if err != nil {
+			return nil, nil
+		}
+
+		return key, val
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	bolt "github.com/coreos/bbolt"
+)
+
+// OpenBolt opens (creating if necessary) a bbolt-backed KV at path. Databases written by the
+// pre-storage-interface Chain remain readable through it: the bucket layout is unchanged, only
+// the Go types wrapping it are new.
+func OpenBolt(path string) (KV, error) {
+	db, err := bolt.Open(path, 0600, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &boltKV{db: db}, nil
+}
+
+type boltKV struct {
+	db *bolt.DB
+}
+
+func (k *boltKV) Update(fn func(Tx) error) error {
+	return k.db.Update(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx})
+	})
+}
+
+func (k *boltKV) View(fn func(Tx) error) error {
+	return k.db.View(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx})
+	})
+}
+
+func (k *boltKV) Close() error {
+	return k.db.Close()
+}
+
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+func (t boltTx) Bucket(name []byte) Bucket {
+	b := t.tx.Bucket(name)
+
+	if b == nil {
+		return nil
+	}
+
+	return boltBucket{b}
+}
+
+func (t boltTx) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	b, err := t.tx.CreateBucketIfNotExists(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return boltBucket{b}, nil
+}
+
+type boltBucket struct {
+	b *bolt.Bucket
+}
+
+func (b boltBucket) Get(key []byte) []byte {
+	return b.b.Get(key)
+}
+
+func (b boltBucket) Put(key, value []byte) error {
+	return b.b.Put(key, value)
+}
+
+func (b boltBucket) Bucket(name []byte) Bucket {
+	sub := b.b.Bucket(name)
+
+	if sub == nil {
+		return nil
+	}
+
+	return boltBucket{sub}
+}
+
+func (b boltBucket) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	sub, err := b.b.CreateBucketIfNotExists(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return boltBucket{sub}, nil
+}
+
+func (b boltBucket) Cursor() Cursor {
+	return boltCursor{b.b.Cursor()}
+}
+
+type boltCursor struct {
+	c *bolt.Cursor
+}
+
+func (c boltCursor) First() ([]byte, []byte) {
+	return c.c.First()
+}
+
+func (c boltCursor) Next() ([]byte, []byte) {
+	return c.c.Next()
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+// Codec is the single seam between sqlchain's fork-choice/reorg code and the wire format its
+// blocks and state are stored in. Callers already have a type-specific marshal/unmarshal method
+// pair (SignedHeader.marshal, State.marshal, ...); Codec just gives the call site one name to
+// call regardless of what that pair actually does, so migrating the wire format to, say,
+// MessagePack or protobuf is a change to a single Codec implementation instead of to every call
+// site that persists a block.
+type Codec interface {
+	// Encode runs marshal and returns its result, after whatever the codec adds on top (framing,
+	// compression, a version byte, ...).
+	Encode(marshal func() ([]byte, error)) ([]byte, error)
+	// Decode strips whatever the codec added on top of data and runs unmarshal on what's left.
+	Decode(data []byte, unmarshal func([]byte) error) error
+}
+
+// identityCodec is the default Codec: it passes data straight through, unmodified, to whatever
+// marshal/unmarshal pair the caller already has.
+type identityCodec struct{}
+
+func (identityCodec) Encode(marshal func() ([]byte, error)) ([]byte, error) {
+	return marshal()
+}
+
+func (identityCodec) Decode(data []byte, unmarshal func([]byte) error) error {
+	return unmarshal(data)
+}
+
+// DefaultCodec is the Codec sqlchain uses for every block and state it persists; sqlchain.Config
+// has no field for choosing another one.
+var DefaultCodec Codec = identityCodec{}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"sort"
+	"sync"
+)
+
+// OpenMemory returns a KV backed by nothing but process memory. It exists so that Chain's tests
+// don't have to touch the filesystem, and is not durable across restarts.
+func OpenMemory() KV {
+	return &memKV{root: newMemBucket()}
+}
+
+type memKV struct {
+	mu   sync.Mutex
+	root *memBucket
+}
+
+func (k *memKV) Update(fn func(Tx) error) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return fn(memTx{k.root})
+}
+
+func (k *memKV) View(fn func(Tx) error) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return fn(memTx{k.root})
+}
+
+func (k *memKV) Close() error {
+	return nil
+}
+
+type memTx struct {
+	root *memBucket
+}
+
+func (t memTx) Bucket(name []byte) Bucket {
+	return t.root.Bucket(name)
+}
+
+func (t memTx) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	return t.root.CreateBucketIfNotExists(name)
+}
+
+// memBucket is both a Bucket and the backing store for its nested buckets.
+type memBucket struct {
+	data    map[string][]byte
+	buckets map[string]*memBucket
+}
+
+func newMemBucket() *memBucket {
+	return &memBucket{data: make(map[string][]byte), buckets: make(map[string]*memBucket)}
+}
+
+func (b *memBucket) Get(key []byte) []byte {
+	v, ok := b.data[string(key)]
+
+	if !ok {
+		return nil
+	}
+
+	cp := make([]byte, len(v))
+	copy(cp, v)
+	return cp
+}
+
+func (b *memBucket) Put(key, value []byte) error {
+	cp := make([]byte, len(value))
+	copy(cp, value)
+	b.data[string(key)] = cp
+	return nil
+}
+
+func (b *memBucket) Bucket(name []byte) Bucket {
+	sub, ok := b.buckets[string(name)]
+
+	if !ok {
+		return nil
+	}
+
+	return sub
+}
+
+func (b *memBucket) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	sub, ok := b.buckets[string(name)]
+
+	if !ok {
+		sub = newMemBucket()
+		b.buckets[string(name)] = sub
+	}
+
+	return sub, nil
+}
+
+func (b *memBucket) Cursor() Cursor {
+	keys := make([]string, 0, len(b.data))
+
+	for k := range b.data {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return &memCursor{bucket: b, keys: keys, pos: -1}
+}
+
+type memCursor struct {
+	bucket *memBucket
+	keys   []string
+	pos    int
+}
+
+func (c *memCursor) First() ([]byte, []byte) {
+	c.pos = 0
+	return c.at(c.pos)
+}
+
+func (c *memCursor) Next() ([]byte, []byte) {
+	c.pos++
+	return c.at(c.pos)
+}
+
+func (c *memCursor) at(i int) ([]byte, []byte) {
+	if i < 0 || i >= len(c.keys) {
+		return nil, nil
+	}
+
+	key := c.keys[i]
+	return []byte(key), c.bucket.Get([]byte(key))
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMemoryKVPutGetAcrossTransactions(t *testing.T) {
+	kv := OpenMemory()
+
+	err := kv.Update(func(tx Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte("b"))
+
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte("k"), []byte("v"))
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = kv.View(func(tx Tx) error {
+		bucket := tx.Bucket([]byte("b"))
+
+		if bucket == nil {
+			t.Fatal("expected bucket b to exist")
+		}
+
+		if got := bucket.Get([]byte("k")); !bytes.Equal(got, []byte("v")) {
+			t.Fatalf("got %q, want %q", got, "v")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemoryKVNestedBucketsAndCursorOrder(t *testing.T) {
+	kv := OpenMemory()
+
+	err := kv.Update(func(tx Tx) error {
+		top, err := tx.CreateBucketIfNotExists([]byte("top"))
+
+		if err != nil {
+			return err
+		}
+
+		nested, err := top.CreateBucketIfNotExists([]byte("nested"))
+
+		if err != nil {
+			return err
+		}
+
+		for _, k := range []string{"c", "a", "b"} {
+			if err := nested.Put([]byte(k), []byte(k)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = kv.View(func(tx Tx) error {
+		nested := tx.Bucket([]byte("top")).Bucket([]byte("nested"))
+		var got []string
+		cursor := nested.Cursor()
+
+		for k, _ := cursor.First(); k != nil; k, _ = cursor.Next() {
+			got = append(got, string(k))
+		}
+
+		want := []string{"a", "b", "c"}
+
+		if len(got) != len(want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemoryKVMissingBucketIsNil(t *testing.T) {
+	kv := OpenMemory()
+
+	err := kv.View(func(tx Tx) error {
+		if tx.Bucket([]byte("nope")) != nil {
+			t.Fatal("expected a missing bucket to be nil")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package storage abstracts the key/value store sqlchain persists its block index and state to,
+// so that fork-choice and reorg code never has to know whether it is ultimately talking to
+// bbolt, an in-memory map used by tests, or a badger database chosen for write throughput.
+package storage
+
+// Cursor iterates over the key/value pairs of a Bucket in ascending key order.
+type Cursor interface {
+	// First seeks to and returns the first key/value pair, or (nil, nil) if the bucket is empty.
+	First() (key, value []byte)
+	// Next advances to and returns the next key/value pair, or (nil, nil) past the last one.
+	Next() (key, value []byte)
+}
+
+// Bucket is a named collection of key/value pairs that may itself contain nested buckets.
+type Bucket interface {
+	// Get returns the value for key, or nil if it is not present.
+	Get(key []byte) []byte
+	// Put sets the value for key, creating or overwriting it.
+	Put(key, value []byte) error
+	// Bucket returns the nested bucket with the given name, or nil if it does not exist.
+	Bucket(name []byte) Bucket
+	// CreateBucketIfNotExists returns the nested bucket with the given name, creating it first
+	// if necessary.
+	CreateBucketIfNotExists(name []byte) (Bucket, error)
+	// Cursor returns a Cursor over this bucket's own key/value pairs (not its nested buckets).
+	Cursor() Cursor
+}
+
+// Tx is a single read or read/write pass over a KV, rooted at the store's top-level buckets.
+type Tx interface {
+	// Bucket returns the top-level bucket with the given name, or nil if it does not exist.
+	Bucket(name []byte) Bucket
+	// CreateBucketIfNotExists returns the top-level bucket with the given name, creating it
+	// first if necessary.
+	CreateBucketIfNotExists(name []byte) (Bucket, error)
+}
+
+// KV is a transactional, bucketed key/value store. Chain depends only on this interface, never
+// on a specific backend.
+type KV interface {
+	// Update runs fn in a read/write transaction; fn's error, if any, aborts the transaction.
+	Update(fn func(Tx) error) error
+	// View runs fn in a read-only transaction.
+	View(fn func(Tx) error) error
+	// Close releases any resources held by the store.
+	Close() error
+}
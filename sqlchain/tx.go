@@ -0,0 +1,26 @@
+/*
+ * Copyright 2018 The ThunderDB Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlchain
+
+import "gitlab.com/thunderdb/ThunderDB/crypto/hash"
+
+// Tx is a pending transaction as seen by the tx pool. It is intentionally minimal: the pool
+// itself lives above this package, which only needs enough of its shape to identify a tx in
+// NewPendingTxEvent/RemovedTxEvent.
+type Tx struct {
+	Hash hash.Hash
+}